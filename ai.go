@@ -0,0 +1,208 @@
+package main
+
+import (
+	"math"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+const (
+	aiSpeed       = 1.2 // default movement in map units per second
+	aiSightRange  = 10.0
+	aiFieldOfView = 2 * math.Pi / 3
+	aiRepathTime  = 0.5 // seconds between a sprite looking for a new path, or retrying after finding none
+	aiChaseStop   = 1.0 // how close chasing sprites come to the player, and how far they can reach to attack
+	aiAttackTime  = 1.0 // seconds between attacks
+	aiFleeSteps   = 8   // how many cells away a fleeing sprite looks for somewhere to run
+)
+
+type aiState int
+
+const (
+	aiIdle aiState = iota
+	aiPatrol
+	aiChase
+	aiFlee
+)
+
+// AI moves a sprite around the ground level of the map. Sprites stand idle, or patrol their
+// waypoints if they have any, until they see the player ahead of them. They then chase the
+// player, or run away once their health drops below FleeHealth, and when the player is out of
+// sight they go to where the player was last seen before returning to what they were doing.
+type AI struct {
+	Waypoints   []geom.Vector2
+	Speed       float64 // map units per second
+	SightRange  float64
+	FieldOfView float64 // in radians, the player is noticed anywhere in sight once being chased or fled from
+	FleeHealth  float64 // fraction of max health below which the sprite flees, 0 never flees
+	Damage      float64 // dealt to the player by each attack, 0 never attacks
+
+	state       aiState
+	waypoint    int
+	path        []mapCell
+	repathTimer float64 // seconds until the path may be searched for again
+	attackTimer float64 // seconds until the sprite can attack again
+	striking    bool    // the attack clip is playing, with its blow still to land
+	lastSeen    *geom.Vector2
+}
+
+func NewAI(speed, sightRange, fieldOfView, fleeHealth, damage float64, waypoints []geom.Vector2) *AI {
+	ai := &AI{
+		Waypoints:   waypoints,
+		Speed:       speed,
+		SightRange:  sightRange,
+		FieldOfView: fieldOfView,
+		FleeHealth:  fleeHealth,
+		Damage:      damage,
+	}
+	ai.state = ai.defaultState()
+	return ai
+}
+
+// defaultState is what the sprite does while it has not seen the player
+func (ai *AI) defaultState() aiState {
+	if len(ai.Waypoints) > 0 {
+		return aiPatrol
+	}
+	return aiIdle
+}
+
+func (ai *AI) setState(state aiState) {
+	if ai.state == state {
+		return
+	}
+	ai.state = state
+	ai.path = nil
+	ai.repathTimer = 0
+}
+
+// canSeePlayer returns true if the player is in the sprite's view with nothing blocking it
+func (g *Game) canSeePlayer(s *Sprite) bool {
+	if g.playerDead {
+		return false
+	}
+	fov := s.AI.FieldOfView
+	if s.AI.state == aiChase || s.AI.state == aiFlee {
+		fov = geom.Pi2
+	}
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	return currentMap.entityInView(s.Entity, fov, s.AI.SightRange, g.player.Entity)
+}
+
+// updateAI chooses what the sprite is doing and sets its heading and velocity to do it
+func (g *Game) updateAI(s *Sprite) {
+	ai := s.AI
+	sees := g.canSeePlayer(s)
+	if sees {
+		ai.lastSeen = g.player.Position.Copy()
+	}
+
+	wounded := ai.FleeHealth > 0 && s.MaxHealth > 0 && s.Health < s.MaxHealth*ai.FleeHealth
+	switch {
+	case sees && wounded:
+		ai.setState(aiFlee)
+	case sees:
+		ai.setState(aiChase)
+	case ai.state == aiChase && ai.lastSeen != nil:
+		// keep heading for where the player was last seen
+	case ai.state == aiFlee && len(ai.path) > 0:
+		// keep running until out of places to run to
+	default:
+		ai.lastSeen = nil
+		ai.setState(ai.defaultState())
+	}
+
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	here := mapCell{x: int(s.Position.X), y: int(s.Position.Y)}
+	ai.repathTimer = math.Max(ai.repathTimer-g.dt, 0)
+	ai.attackTimer = math.Max(ai.attackTimer-g.dt, 0)
+	g.landBlow(s)
+
+	switch ai.state {
+	case aiIdle:
+		ai.path = nil
+	case aiPatrol:
+		if len(ai.path) == 0 && ai.repathTimer == 0 {
+			wp := ai.Waypoints[ai.waypoint]
+			target := mapCell{x: int(wp.X), y: int(wp.Y)}
+			if target == here {
+				ai.waypoint = (ai.waypoint + 1) % len(ai.Waypoints)
+			} else if ai.path = currentMap.findPath(here, target, s.CollisionRadius); ai.path == nil {
+				// skip waypoints that cannot be reached, waiting before searching again so that
+				// a sprite with none it can reach does not search the whole map every tick
+				ai.waypoint = (ai.waypoint + 1) % len(ai.Waypoints)
+				ai.repathTimer = aiRepathTime
+			}
+		}
+	case aiChase:
+		if sees && geom.Distance(s.Position.X, s.Position.Y, g.player.Position.X, g.player.Position.Y) <= aiChaseStop {
+			ai.path = nil
+			s.Angle = math.Atan2(g.player.Position.Y-s.Position.Y, g.player.Position.X-s.Position.X)
+			g.aiAttack(s)
+			break
+		}
+		if len(ai.path) == 0 || ai.repathTimer == 0 {
+			target := mapCell{x: int(ai.lastSeen.X), y: int(ai.lastSeen.Y)}
+			ai.path = currentMap.findPath(here, target, s.CollisionRadius)
+			ai.repathTimer = aiRepathTime
+			if len(ai.path) == 0 && !sees {
+				// reached the last place the player was seen, or cannot get there
+				ai.lastSeen = nil
+			}
+		}
+	case aiFlee:
+		if len(ai.path) == 0 || ai.repathTimer == 0 {
+			ai.path = currentMap.findFleePath(here, g.player.Position.X, g.player.Position.Y, aiFleeSteps, s.CollisionRadius)
+			ai.repathTimer = aiRepathTime
+		}
+	}
+
+	ai.followPath(s, g.dt)
+}
+
+// aiAttack attacks the player within reach, at most once every aiAttackTime seconds. Sprites
+// without an attack clip hit straight away, the others land the blow when the clip ends.
+func (g *Game) aiAttack(s *Sprite) {
+	ai := s.AI
+	if ai.Damage <= 0 || ai.attackTimer > 0 || ai.striking {
+		return
+	}
+	ai.attackTimer = aiAttackTime
+	if s.PlayClip("attack") {
+		ai.striking = true
+		return
+	}
+	g.damage(g.player.Entity, s.Entity, ai.Damage)
+}
+
+// landBlow hits the player with a pending attack once the attack clip has completed or been
+// replaced by another clip, if the player is still within reach
+func (g *Game) landBlow(s *Sprite) {
+	ai := s.AI
+	if !ai.striking || (s.CurrentClip() == "attack" && !s.ClipDone() && s.LoopCounter() == 0) {
+		return
+	}
+	ai.striking = false
+	if !g.playerDead && geom.Distance(s.Position.X, s.Position.Y, g.player.Position.X, g.player.Position.Y) <= aiChaseStop {
+		g.damage(g.player.Entity, s.Entity, ai.Damage)
+	}
+}
+
+// followPath heads the sprite for the middle of the next cell in its path, slowing down to
+// arrive there rather than overshoot it in a tick of dt seconds
+func (ai *AI) followPath(s *Sprite, dt float64) {
+	for len(ai.path) > 0 {
+		next := ai.path[0]
+		x, y := float64(next.x)+0.5, float64(next.y)+0.5
+		dist := geom.Distance(s.Position.X, s.Position.Y, x, y)
+		if dist < 0.01 {
+			ai.path = ai.path[1:]
+			continue
+		}
+
+		s.Angle = math.Atan2(y-s.Position.Y, x-s.Position.X)
+		s.Velocity = math.Min(ai.Speed, dist/dt)
+		return
+	}
+	s.Velocity = 0
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// aiGame returns a game on a 7x7 map with an open ring around a walled in cell at (3,3), the
+// player standing in the ring at (1.5,1.5)
+func aiGame(t *testing.T) *Game {
+	t.Helper()
+	m, err := parseMap(strings.NewReader(`walltexture 1 stone
+walls
+1111111
+1000001
+1011101
+1010101
+1011101
+1000001
+1111111
+end
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.clearSprites()
+
+	g := &Game{gameLevels: &gameLevels{levelMaps: []*Map{m}}, dt: 1.0 / defaultTPS}
+	g.player = newSpawnedPlayer(m)
+	return g
+}
+
+func aiSprite(x, y float64, ai *AI) *Sprite {
+	return &Sprite{
+		Entity: &Entity{Position: &geom.Vector2{X: x, Y: y}, CollisionRadius: 0.2, CollisionHeight: 0.5},
+		AI:     ai,
+	}
+}
+
+func TestPatrolUnreachableWaypointsWaitToRetry(t *testing.T) {
+	g := aiGame(t)
+	waypoints := make([]geom.Vector2, 16)
+	for i := range waypoints {
+		waypoints[i] = geom.Vector2{X: 5.5, Y: 5.5}
+	}
+	// walled in, so no waypoint can be reached
+	s := aiSprite(3.5, 3.5, NewAI(aiSpeed, aiSightRange, aiFieldOfView, 0, 0, waypoints))
+
+	for i := 0; i < 10; i++ {
+		g.updateAI(s)
+	}
+	if s.AI.waypoint != 1 {
+		t.Fatalf("skipped %d waypoints in 10 ticks, want 1", s.AI.waypoint)
+	}
+
+	ticks := int(aiRepathTime/g.dt) + 10
+	for i := 0; i < ticks; i++ {
+		g.updateAI(s)
+	}
+	if s.AI.waypoint != 2 {
+		t.Errorf("skipped %d waypoints after waiting %v seconds, want 2", s.AI.waypoint, aiRepathTime)
+	}
+	if s.Velocity != 0 {
+		t.Errorf("got velocity %v with nowhere to go", s.Velocity)
+	}
+}
+
+// attacker returns a sprite facing the player from within reach, with a two frame attack clip
+// lasting 0.2 seconds if withClip is set
+func attacker(g *Game, withClip bool, next string) *Sprite {
+	s := aiSprite(1.5, 2.3, NewAI(aiSpeed, aiSightRange, aiFieldOfView, 0, 10, nil))
+	s.Angle = -math.Pi / 2
+	if withClip {
+		s.columns, s.rows, s.lenTex = 2, 1, 2
+		s.AddClip(AnimationClip{Name: "attack", First: 0, Last: 1, Rate: 10, Mode: AnimationOnce, Next: next})
+	}
+	return s
+}
+
+// tickAI runs the sprite's AI and animation for the given number of ticks
+func tickAI(g *Game, s *Sprite, ticks int) {
+	for i := 0; i < ticks; i++ {
+		g.updateAI(s)
+		s.playMovementClip()
+		s.Update(g.player.Position, g.dt)
+	}
+}
+
+func TestAIAttack(t *testing.T) {
+	clipTicks := int(0.2/(1.0/defaultTPS)) + 2
+
+	t.Run("without clip hits straight away", func(t *testing.T) {
+		g := aiGame(t)
+		tickAI(g, attacker(g, false, ""), 1)
+		if len(g.damageEvents) != 1 {
+			t.Errorf("got %d hits, want 1", len(g.damageEvents))
+		}
+	})
+
+	t.Run("blow lands when the clip completes", func(t *testing.T) {
+		g := aiGame(t)
+		s := attacker(g, true, "")
+		tickAI(g, s, 1)
+		if len(g.damageEvents) != 0 || s.CurrentClip() != "attack" {
+			t.Fatalf("got %d hits playing %q, want the attack clip started and no hit yet", len(g.damageEvents), s.CurrentClip())
+		}
+		tickAI(g, s, clipTicks)
+		if len(g.damageEvents) != 1 {
+			t.Errorf("got %d hits after the clip, want 1", len(g.damageEvents))
+		}
+	})
+
+	t.Run("blow lands when the clip moves on", func(t *testing.T) {
+		g := aiGame(t)
+		s := attacker(g, true, "recover")
+		s.AddClip(AnimationClip{Name: "recover", First: 0, Last: 1, Rate: 10, Mode: AnimationLoop})
+		tickAI(g, s, clipTicks)
+		if len(g.damageEvents) != 1 {
+			t.Errorf("got %d hits playing %q, want 1", len(g.damageEvents), s.CurrentClip())
+		}
+	})
+
+	t.Run("blow misses once out of reach", func(t *testing.T) {
+		g := aiGame(t)
+		s := attacker(g, true, "")
+		tickAI(g, s, 1)
+		g.player.Position = &geom.Vector2{X: 1.5, Y: 4.5}
+		tickAI(g, s, clipTicks)
+		if len(g.damageEvents) != 0 {
+			t.Errorf("got %d hits on a player out of reach, want 0", len(g.damageEvents))
+		}
+	})
+
+	t.Run("one blow a second", func(t *testing.T) {
+		g := aiGame(t)
+		tickAI(g, attacker(g, true, ""), 2*defaultTPS+clipTicks)
+		if len(g.damageEvents) != 3 {
+			t.Errorf("got %d hits in two seconds, want 3", len(g.damageEvents))
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// AnimationMode is how a clip continues once it reaches its last frame
+type AnimationMode int
+
+const (
+	// AnimationLoop starts again from the first frame
+	AnimationLoop AnimationMode = iota
+	// AnimationOnce holds the last frame
+	AnimationOnce
+	// AnimationPingPong plays back down to the first frame, then up again
+	AnimationPingPong
+)
+
+var animationModes = map[string]AnimationMode{
+	"loop":     AnimationLoop,
+	"once":     AnimationOnce,
+	"pingpong": AnimationPingPong,
+}
+
+// AnimationClip is a named range of frames, such as "walk" or "die", played at its own rate.
+// Frames are numbered from the start of the facing row when the sprite has a facing map,
+// otherwise from the start of the sheet, so one row of a sheet can hold several clips.
+type AnimationClip struct {
+	Name        string
+	First, Last int
+	Rate        float64 // frames per second
+	Mode        AnimationMode
+	Next        string // clip to play when this one completes, if any
+}
+
+// ClipDoneFunc is called each time a sprite's clip completes, once per cycle for clips that repeat
+type ClipDoneFunc func(s *Sprite, clip string)
+
+// AddClip adds an animation clip to the sprite, replacing any clip with the same name
+func (s *Sprite) AddClip(clip AnimationClip) {
+	if s.clips == nil {
+		s.clips = make(map[string]*AnimationClip)
+	}
+	s.clips[clip.Name] = &clip
+}
+
+// HasClip returns true if the sprite has a clip with the given name
+func (s *Sprite) HasClip(name string) bool {
+	_, ok := s.clips[name]
+	return ok
+}
+
+// PlayClip switches the sprite to the named clip from its first frame, unless that clip is
+// already playing. Returns false if the sprite has no such clip.
+func (s *Sprite) PlayClip(name string) bool {
+	clip, ok := s.clips[name]
+	if !ok {
+		return false
+	}
+	if s.clip == clip && !s.clipDone {
+		return true
+	}
+
+	s.clip = clip
+	s.clipFrame = clip.First
+	s.clipStep = 1
+	s.clipDone = false
+	s.animTime = 0
+	s.loopCounter = 0
+	return true
+}
+
+// CurrentClip returns the name of the clip being played, or "" if the sprite is not playing clips
+func (s *Sprite) CurrentClip() string {
+	if s.clip == nil {
+		return ""
+	}
+	return s.clip.Name
+}
+
+// ClipDone returns true once a clip that plays once has reached its last frame
+func (s *Sprite) ClipDone() bool {
+	return s.clipDone
+}
+
+// EndClip stops the current clip on its last frame, as if a clip that plays once had completed
+func (s *Sprite) EndClip() {
+	if s.clip == nil {
+		return
+	}
+	s.clipFrame, s.clipStep, s.clipDone = s.clip.Last, 1, true
+}
+
+// updateClip shows the current frame of the clip in the row facing the camera, then advances
+// the clip by dt seconds
+func (s *Sprite) updateClip(camPos *geom.Vector2, dt float64) {
+	s.texNum = s.facingRow(camPos)*s.columns + s.clipFrame
+	if s.clipDone {
+		return
+	}
+	clip := s.clip
+	for steps := s.animationSteps(dt, clip.Rate); steps > 0; steps-- {
+		s.advanceClip()
+		if s.clipDone || s.clip != clip {
+			// a transition to the next clip starts that clip's timing afresh
+			break
+		}
+	}
+}
+
+func (s *Sprite) advanceClip() {
+	clip := s.clip
+	next := s.clipFrame + s.clipStep
+	if next >= clip.First && next <= clip.Last {
+		s.clipFrame = next
+		return
+	}
+
+	// reached an end of the clip
+	switch clip.Mode {
+	case AnimationLoop:
+		s.clipFrame = clip.First
+	case AnimationOnce:
+		s.clipDone = true
+	case AnimationPingPong:
+		s.clipStep = -s.clipStep
+		if clip.First < clip.Last {
+			s.clipFrame += s.clipStep
+		}
+		if s.clipStep < 0 {
+			// turned at the last frame, the cycle completes back at the first
+			return
+		}
+	}
+	s.loopCounter++
+
+	if s.OnClipDone != nil {
+		s.OnClipDone(s, clip.Name)
+	}
+	if clip.Next != "" && s.clip == clip {
+		s.PlayClip(clip.Next)
+	}
+}
+
+// playMovementClip plays the walk or idle clip to match the sprite's movement, unless it is
+// playing some other clip such as an attack
+func (s *Sprite) playMovementClip() {
+	switch s.CurrentClip() {
+	case "", "idle", "walk":
+	default:
+		if !s.clipDone || s.IsDead() {
+			return
+		}
+	}
+
+	name := "idle"
+	if s.Velocity != 0 && s.HasClip("walk") {
+		name = "walk"
+	}
+	// only start over when the movement changes, so a finished idle clip that plays once stays
+	// on its last frame
+	if name != s.CurrentClip() {
+		s.PlayClip(name)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// clipSprite returns a sprite with a once idle clip over frames 0-1 and a looping walk clip over
+// frames 2-3, both at 10 frames per second
+func clipSprite() *Sprite {
+	s := &Sprite{Entity: &Entity{Position: &geom.Vector2{}}}
+	s.columns, s.rows, s.lenTex = 4, 1, 4
+	s.AddClip(AnimationClip{Name: "idle", First: 0, Last: 1, Rate: 10, Mode: AnimationOnce})
+	s.AddClip(AnimationClip{Name: "walk", First: 2, Last: 3, Rate: 10, Mode: AnimationLoop})
+	return s
+}
+
+// tick updates the sprite's clip for its movement then advances it by a tenth of a second
+func tick(s *Sprite) {
+	s.playMovementClip()
+	s.Update(nil, 0.1)
+}
+
+func TestMovementClipOnceIdleStaysDone(t *testing.T) {
+	s := clipSprite()
+	var done []string
+	s.OnClipDone = func(s *Sprite, clip string) { done = append(done, clip) }
+
+	for i := 0; i < 10; i++ {
+		tick(s)
+	}
+	if s.CurrentClip() != "idle" || !s.ClipDone() || s.clipFrame != 1 {
+		t.Fatalf("got clip %q frame %d done %v, want idle held on frame 1", s.CurrentClip(), s.clipFrame, s.ClipDone())
+	}
+	if len(done) != 1 {
+		t.Errorf("idle completed %d times, want once", len(done))
+	}
+
+	// moving switches to walk, and stopping plays idle through once more
+	s.Velocity = 1
+	tick(s)
+	if s.CurrentClip() != "walk" {
+		t.Fatalf("got clip %q while moving, want walk", s.CurrentClip())
+	}
+	s.Velocity = 0
+	tick(s)
+	if s.CurrentClip() != "idle" || s.ClipDone() || s.clipFrame != 1 {
+		t.Errorf("got clip %q frame %d done %v after stopping, want idle restarted", s.CurrentClip(), s.clipFrame, s.ClipDone())
+	}
+}
+
+func TestMovementClipWaitsForOtherClip(t *testing.T) {
+	s := clipSprite()
+	s.AddClip(AnimationClip{Name: "attack", First: 0, Last: 3, Rate: 10, Mode: AnimationOnce})
+	s.PlayClip("attack")
+
+	for i := 0; i < 3; i++ {
+		tick(s)
+		if s.CurrentClip() != "attack" {
+			t.Fatalf("attack interrupted by %q after %d ticks", s.CurrentClip(), i+1)
+		}
+	}
+	tick(s)
+	tick(s)
+	if s.CurrentClip() != "idle" {
+		t.Errorf("got clip %q after attack completed, want idle", s.CurrentClip())
+	}
+}
+
+func TestDieClipHoldsLastFrame(t *testing.T) {
+	s := clipSprite()
+	s.columns, s.lenTex = 6, 6
+	s.AddClip(AnimationClip{Name: "die", First: 4, Last: 5, Rate: 10, Mode: AnimationLoop})
+	s.Health, s.MaxHealth = 0, 10
+
+	(&Game{}).killSprite(s)
+	for i := 0; i < 10; i++ {
+		tick(s)
+	}
+	if s.CurrentClip() != "die" || !s.ClipDone() || s.clipFrame != 5 {
+		t.Errorf("got clip %q frame %d done %v, want die held on frame 5", s.CurrentClip(), s.clipFrame, s.ClipDone())
+	}
+}
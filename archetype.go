@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image/color"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/harbdog/raycaster-go"
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// The sprite archetype file describes each kind of sprite that maps can place. Blank lines
+// are ignored and anything following a '#' is treated as a comment. Each archetype is a block
+//
+//	archetype <name>
+//	<properties>
+//	end
+//
+// with the following properties, of which only texture is required:
+//
+//	texture <name>
+//	    the named sprite texture from the texture manifest
+//
+//	sheet <columns> <rows>
+//	    splits the texture into a sheet of equally sized frames (default 1 1)
+//
+//	rate <fps>
+//	    animates through the frames at the given frames per second (default 0, not animated,
+//	    shows the first frame)
+//
+//	anchor bottom|center|top
+//	    which part of the sprite is anchored to its Z-position (default bottom)
+//
+//	scale <n>
+//	    size relative to a wall cell (default 1)
+//
+//	collision <radiusPx> <heightPx>
+//	    collision radius and height in pixels of an unscaled frame (default 0 0, no collision)
+//
+//	color <mapColor>
+//	    hex RRGGBB or RRGGBBAA color of the sprite on the minimap (default white)
+//
+//	facing <angleDegrees>=<row> ...
+//	facing <directions>
+//	    sheet row to use when the sprite is seen from each angle relative to its heading,
+//	    the closest listed angle is used, or the number of evenly spaced directions for a
+//	    sheet with one row per direction starting from the front and going counter-clockwise
+//
+//	clip <name> <first> <last> <fps> [loop|once|pingpong] [next=<clip>]
+//	    a named animation clip over frames first to last, counted from the start of the
+//	    facing row when there is a facing map otherwise from the start of the sheet, played
+//	    at the given frames per second, repeat for each clip. The first clip plays when the sprite
+//	    is placed, and next names the clip to switch to when this one completes (default
+//	    loop, no next). Sprites play their idle and walk clips as they stop and move, and
+//	    their die clip when killed.
+//
+//	ai [speed=<n>] [sight=<n>] [fov=<degrees>] [flee=<fraction>] [damage=<n>]
+//	    the sprite moves on its own, idling or patrolling the waypoints given where it is
+//	    placed until it sees the player within sight cells (default 10) and fov degrees of
+//	    its heading (default 120), then chasing the player at speed cells per second (default
+//	    1.2), or running away once its health falls below the given fraction of its
+//	    maximum (default 0, never runs). Within reach of the player it attacks once a second
+//	    for damage health (default 0, harmless), landing the blow when its attack clip
+//	    completes if it has one and the player is still within reach
+const spriteArchetypeFile = "resources/sprites.txt"
+
+// spriteArchetype describes how to create a kind of sprite
+type spriteArchetype struct {
+	name          string
+	texName       string
+	image         *ebiten.Image
+	columns, rows int
+	animationRate float64
+	anchor        raycaster.SpriteAnchor
+	scale         float64
+	collisionPxR  float64
+	collisionPxH  float64
+	mapColor      color.RGBA
+	facing        map[float64]int
+	clips         []AnimationClip
+	ai            *AI // settings copied to each sprite, nil if the sprite has no AI
+}
+
+var spriteAnchors = map[string]raycaster.SpriteAnchor{
+	"bottom": raycaster.AnchorBottom,
+	"center": raycaster.AnchorCenter,
+	"top":    raycaster.AnchorTop,
+}
+
+// loadSpriteArchetypes reads the archetype file and looks up the texture of each archetype,
+// reporting all invalid archetypes and unknown textures together
+func loadSpriteArchetypes(path string, registry *textureRegistry) (map[string]*spriteArchetype, error) {
+	f, err := embedded.Open(filepath.ToSlash(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	archetypes := make(map[string]*spriteArchetype)
+	var errs []error
+	var current *spriteArchetype
+	startLine := 0
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if current == nil {
+			if fields[0] != "archetype" || len(fields) != 2 {
+				errs = append(errs, fmt.Errorf("%s line %d: expected archetype <name>", path, lineNum))
+				continue
+			}
+			if _, ok := archetypes[fields[1]]; ok {
+				errs = append(errs, fmt.Errorf("%s line %d: duplicate archetype %q", path, lineNum, fields[1]))
+			}
+			current = &spriteArchetype{
+				name:     fields[1],
+				columns:  1,
+				rows:     1,
+				anchor:   raycaster.AnchorBottom,
+				scale:    1.0,
+				mapColor: color.RGBA{255, 255, 255, 255},
+			}
+			startLine = lineNum
+			continue
+		}
+
+		if fields[0] == "end" {
+			if err := current.resolve(registry); err != nil {
+				errs = append(errs, fmt.Errorf("%s line %d: archetype %q: %w", path, startLine, current.name, err))
+			} else if _, ok := archetypes[current.name]; !ok {
+				archetypes[current.name] = current
+			}
+			current = nil
+			continue
+		}
+		if err := current.setProperty(fields); err != nil {
+			errs = append(errs, fmt.Errorf("%s line %d: %s: %w", path, lineNum, fields[0], err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		errs = append(errs, fmt.Errorf("%s line %d: archetype %q has no end", path, startLine, current.name))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return archetypes, nil
+}
+
+// setProperty sets a single property of the archetype from a line of its block
+func (a *spriteArchetype) setProperty(fields []string) error {
+	args := fields[1:]
+	switch fields[0] {
+	case "texture":
+		if len(args) != 1 {
+			return fmt.Errorf("expected a texture name")
+		}
+		a.texName = args[0]
+	case "sheet":
+		if len(args) != 2 {
+			return fmt.Errorf("expected 2 values, got %d", len(args))
+		}
+		columns, errC := strconv.Atoi(args[0])
+		rows, errR := strconv.Atoi(args[1])
+		if errC != nil || errR != nil || columns <= 0 || rows <= 0 {
+			return fmt.Errorf("invalid sheet size %q %q", args[0], args[1])
+		}
+		a.columns, a.rows = columns, rows
+	case "rate":
+		if len(args) != 1 {
+			return fmt.Errorf("expected 1 value, got %d", len(args))
+		}
+		rate, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || rate < 0 {
+			return fmt.Errorf("invalid rate %q", args[0])
+		}
+		a.animationRate = rate
+	case "anchor":
+		if len(args) != 1 {
+			return fmt.Errorf("expected 1 value, got %d", len(args))
+		}
+		anchor, ok := spriteAnchors[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown anchor %q", args[0])
+		}
+		a.anchor = anchor
+	case "scale":
+		vals, err := parseMapFloats(args, 1)
+		if err != nil {
+			return err
+		}
+		if vals[0] <= 0 {
+			return fmt.Errorf("scale must be positive")
+		}
+		a.scale = vals[0]
+	case "collision":
+		vals, err := parseMapFloats(args, 2)
+		if err != nil {
+			return err
+		}
+		if vals[0] < 0 || vals[1] < 0 {
+			return fmt.Errorf("collision size must not be negative")
+		}
+		a.collisionPxR, a.collisionPxH = vals[0], vals[1]
+	case "color":
+		if len(args) != 1 {
+			return fmt.Errorf("expected 1 value, got %d", len(args))
+		}
+		clr, err := parseHexColor(args[0])
+		if err != nil {
+			return err
+		}
+		a.mapColor = clr
+	case "facing":
+		if len(args) == 0 {
+			return fmt.Errorf("expected at least one <angleDegrees>=<row>")
+		}
+		if directions, err := strconv.Atoi(args[0]); err == nil && len(args) == 1 {
+			if directions <= 0 {
+				return fmt.Errorf("invalid number of directions %d", directions)
+			}
+			a.facing = facingDirections(directions)
+			break
+		}
+		a.facing = make(map[float64]int, len(args))
+		for _, f := range args {
+			angleStr, rowStr, ok := strings.Cut(f, "=")
+			angle, errA := strconv.ParseFloat(angleStr, 64)
+			row, errR := strconv.Atoi(rowStr)
+			if !ok || errA != nil || errR != nil || row < 0 {
+				return fmt.Errorf("invalid facing %q", f)
+			}
+			a.facing[geom.Radians(angle)] = row
+		}
+	case "clip":
+		clip, err := parseAnimationClip(args)
+		if err != nil {
+			return err
+		}
+		a.clips = append(a.clips, clip)
+	case "ai":
+		ai, err := parseAI(args)
+		if err != nil {
+			return err
+		}
+		a.ai = ai
+	default:
+		return fmt.Errorf("unknown property")
+	}
+	return nil
+}
+
+func parseAnimationClip(args []string) (AnimationClip, error) {
+	clip := AnimationClip{}
+	if len(args) < 4 {
+		return clip, fmt.Errorf("expected at least 4 values, got %d", len(args))
+	}
+	clip.Name = args[0]
+
+	first, errF := strconv.Atoi(args[1])
+	last, errL := strconv.Atoi(args[2])
+	if errF != nil || errL != nil || first < 0 || last < 0 {
+		return clip, fmt.Errorf("invalid frames %q %q", args[1], args[2])
+	}
+	rate, err := strconv.ParseFloat(args[3], 64)
+	if err != nil || rate <= 0 {
+		return clip, fmt.Errorf("invalid rate %q", args[3])
+	}
+	clip.First, clip.Last, clip.Rate = first, last, rate
+	if clip.Last < clip.First {
+		return clip, fmt.Errorf("last frame %d is before first frame %d", clip.Last, clip.First)
+	}
+
+	for _, f := range args[4:] {
+		if next, ok := strings.CutPrefix(f, "next="); ok {
+			clip.Next = next
+			continue
+		}
+		mode, ok := animationModes[f]
+		if !ok {
+			return clip, fmt.Errorf("unknown clip mode %q", f)
+		}
+		clip.Mode = mode
+	}
+	return clip, nil
+}
+
+func parseAI(args []string) (*AI, error) {
+	ai := &AI{Speed: aiSpeed, SightRange: aiSightRange, FieldOfView: aiFieldOfView}
+	for _, f := range args {
+		key, value, ok := strings.Cut(f, "=")
+		v, err := strconv.ParseFloat(value, 64)
+		if !ok || err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid option %q", f)
+		}
+		switch key {
+		case "speed":
+			ai.Speed = v
+		case "sight":
+			ai.SightRange = v
+		case "fov":
+			ai.FieldOfView = geom.Radians(v)
+		case "flee":
+			if v > 1 {
+				return nil, fmt.Errorf("flee must be a fraction of max health, got %v", v)
+			}
+			ai.FleeHealth = v
+		case "damage":
+			ai.Damage = v
+		default:
+			return nil, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	return ai, nil
+}
+
+// resolve looks up the archetype's texture and checks that it fits the sheet and facing rows
+func (a *spriteArchetype) resolve(registry *textureRegistry) error {
+	if a.texName == "" {
+		return fmt.Errorf("no texture given")
+	}
+	e, err := registry.lookup("sprite", a.texName)
+	if err != nil {
+		return err
+	}
+	w, h := e.image.Bounds().Dx(), e.image.Bounds().Dy()
+	if w%a.columns != 0 || h%a.rows != 0 {
+		return fmt.Errorf("%dx%d texture does not divide into %d columns and %d rows", w, h, a.columns, a.rows)
+	}
+	for _, row := range a.facing {
+		if row >= a.rows {
+			return fmt.Errorf("facing row %d is outside the sheet's %d rows", row, a.rows)
+		}
+	}
+
+	// clip frames are counted within a facing row when there is a facing map
+	frames := a.columns * a.rows
+	if len(a.facing) > 0 {
+		frames = a.columns
+	}
+	names := make(map[string]bool, len(a.clips))
+	for _, clip := range a.clips {
+		if names[clip.Name] {
+			return fmt.Errorf("duplicate clip %q", clip.Name)
+		}
+		names[clip.Name] = true
+		if clip.Last >= frames {
+			return fmt.Errorf("clip %q frame %d is outside the %d frames available", clip.Name, clip.Last, frames)
+		}
+	}
+	for _, clip := range a.clips {
+		if clip.Next != "" && !names[clip.Next] {
+			return fmt.Errorf("clip %q switches to unknown clip %q", clip.Name, clip.Next)
+		}
+	}
+
+	a.image = e.image
+	return nil
+}
+
+// newSprite creates a sprite of this archetype from a map placement
+func (a *spriteArchetype) newSprite(p spritePlacement) *Sprite {
+	scale := a.scale
+	if p.scale > 0 {
+		scale = p.scale
+	}
+	mapColor := a.mapColor
+	if p.mapColor != nil {
+		mapColor = *p.mapColor
+	}
+
+	// collision values are given in pixels of a single unscaled frame
+	frameW := float64(a.image.Bounds().Dx() / a.columns)
+	frameH := float64(a.image.Bounds().Dy() / a.rows)
+	collisionRadius := (scale * a.collisionPxR) / frameW
+	collisionHeight := (scale * a.collisionPxH) / frameH
+
+	var s *Sprite
+	switch {
+	case a.animationRate > 0:
+		s = NewAnimatedSprite(p.x, p.y, scale, a.animationRate, a.image, mapColor,
+			a.columns, a.rows, a.anchor, collisionRadius, collisionHeight)
+	case a.columns*a.rows > 1:
+		s = NewSpriteFromSheet(p.x, p.y, scale, a.image, mapColor,
+			a.columns, a.rows, 0, a.anchor, collisionRadius, collisionHeight)
+	default:
+		s = NewSprite(p.x, p.y, scale, a.image, mapColor, a.anchor, collisionRadius, collisionHeight)
+	}
+	if len(a.facing) > 0 {
+		s.SetTextureFacingMap(a.facing)
+	}
+	for _, clip := range a.clips {
+		s.AddClip(clip)
+	}
+	if len(a.clips) > 0 {
+		s.PlayClip(a.clips[0].Name)
+	}
+	if a.ai != nil {
+		s.AI = NewAI(a.ai.Speed, a.ai.SightRange, a.ai.FieldOfView, a.ai.FleeHealth, a.ai.Damage, p.waypoints)
+	}
+	s.Angle = p.angle
+	return s
+}
@@ -28,9 +28,16 @@ func (g *Game) getValidMove(entity *Entity, moveX, moveY, moveZ float64, checkAl
 	intersectPoints := []geom.Vector2{}
 	collisionEntities := []*EntityCollision{}
 
+	// bounds of the move used to only check nearby walls and sprites
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	minX, maxX := math.Min(posX, newX), math.Max(posX, newX)
+	minY, maxY := math.Min(posY, newY), math.Max(posY, newY)
+
+	// only walls on levels overlapping the entity's height can block it
+	entityMinZ, entityMaxZ := zEntityMinMax(newZ, entity)
+
 	// check wall collisions
-	for _, borderLine := range g.gameLevels.levelMaps[g.gameLevels.currentLevel].collisionMap {
-		// TODO: only check intersection of nearby wall cells instead of all of them
+	for _, borderLine := range currentMap.linesNear(minX, minY, maxX, maxY, entityMinZ, entityMaxZ) {
 		if px, py, ok := geom.LineIntersection(moveLine, borderLine); ok {
 			intersectPoints = append(intersectPoints, geom.Vector2{X: px, Y: py})
 		}
@@ -38,8 +45,6 @@ func (g *Game) getValidMove(entity *Entity, moveX, moveY, moveZ float64, checkAl
 
 	// check sprite against player collision
 	if entity != g.player.Entity && entity.Parent != g.player.Entity && entity.CollisionRadius > 0 {
-		// TODO: only check for collision if player is somewhat nearby
-
 		// quick check if intersects in Z-plane
 		zIntersect := zEntityIntersection(newZ, entity, g.player.Entity)
 
@@ -64,8 +69,8 @@ func (g *Game) getValidMove(entity *Entity, moveX, moveY, moveZ float64, checkAl
 	}
 
 	// check sprite collisions
-	for sprite := range g.gameLevels.levelMaps[g.gameLevels.currentLevel].sprites {
-		// TODO: only check intersection of nearby sprites instead of all of them
+	r := entity.CollisionRadius
+	for _, sprite := range currentMap.spritesNear(minX-r, minY-r, maxX+r, maxY+r) {
 		if entity == sprite.Entity || entity.Parent == sprite.Entity || entity.CollisionRadius <= 0 || sprite.CollisionRadius <= 0 {
 			continue
 		}
@@ -163,8 +168,8 @@ func (g *Game) getValidMove(entity *Entity, moveX, moveY, moveZ float64, checkAl
 	case ix < 0 || newX < 0:
 		newX = 0.2
 		ix = 0
-	case ix >= g.gameLevels.levelMaps[g.gameLevels.currentLevel].xLength:
-		newX = float64(g.gameLevels.levelMaps[g.gameLevels.currentLevel].xLength) - 0.2
+	case ix >= currentMap.xLength:
+		newX = float64(currentMap.xLength) - 0.2
 		ix = int(newX)
 	}
 
@@ -172,13 +177,12 @@ func (g *Game) getValidMove(entity *Entity, moveX, moveY, moveZ float64, checkAl
 	case iy < 0 || newY < 0:
 		newY = 0.2
 		iy = 0
-	case iy >= g.gameLevels.levelMaps[g.gameLevels.currentLevel].yLength:
-		newY = float64(g.gameLevels.levelMaps[g.gameLevels.currentLevel].yLength) - 0.2
+	case iy >= currentMap.yLength:
+		newY = float64(currentMap.yLength) - 0.2
 		iy = int(newY)
 	}
 
-	worldMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel].wallMaps[0]
-	if worldMap[ix][iy] <= 0 {
+	if !currentMap.isWallAt(ix, iy, entityMinZ, entityMaxZ) {
 		posX = newX
 		posY = newY
 	} else {
@@ -188,6 +192,33 @@ func (g *Game) getValidMove(entity *Entity, moveX, moveY, moveZ float64, checkAl
 	return &geom.Vector2{X: posX, Y: posY}, isCollision, collisionEntities
 }
 
+// canStand checks whether the player has room to stand up without hitting a wall or sprite overhead
+func (g *Game) canStand() bool {
+	standing := *g.player.Entity
+	standing.CollisionHeight = playerStandHeight
+
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	standingMinZ, standingMaxZ := zEntityMinMax(standing.PositionZ, &standing)
+	for _, cell := range currentMap.cellsUnder(standing.Position, standing.CollisionRadius) {
+		if currentMap.isWallAt(cell.x, cell.y, standingMinZ, standingMaxZ) {
+			return false
+		}
+	}
+	for _, sprite := range currentMap.spritesNearCircle(standing.Position, standing.CollisionRadius) {
+		if sprite.CollisionRadius <= 0 {
+			continue
+		}
+		dist := geom.Distance(standing.Position.X, standing.Position.Y, sprite.Position.X, sprite.Position.Y)
+		if dist >= standing.CollisionRadius+sprite.CollisionRadius {
+			continue
+		}
+		if zEntityIntersection(standing.PositionZ, &standing, sprite.Entity) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // zEntityIntersection returns the best positionZ intersection point on the target from the source (-1 if no intersection)
 func zEntityIntersection(sourceZ float64, source, target *Entity) float64 {
 	srcMinZ, srcMaxZ := zEntityMinMax(sourceZ, source)
@@ -198,6 +229,10 @@ func zEntityIntersection(sourceZ float64, source, target *Entity) float64 {
 		// no intersection
 		return intersectZ
 	}
+	if target.CollisionHeight > 0 && srcMinZ >= tgtMaxZ-groundEpsilon {
+		// source is resting on top of the target
+		return intersectZ
+	}
 
 	// find best simple intersection within the target range
 	midZ := srcMinZ + (srcMaxZ-srcMinZ)/2
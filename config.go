@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	configName = "config"
+	configType = "yaml"
+	envPrefix  = "GAME"
+
+	// configVersion is written to saved config files, it must be raised whenever the meaning of a
+	// setting changes, with the old values converted in migrateConfig
+	configVersion = 2
+)
+
+// tickSettings were given per tick at a fixed 60 ticks per second before version 2, and are
+// converted to per second by multiplying by the scale
+var tickSettings = []struct {
+	key   string
+	scale float64
+}{
+	{"input.turnSpeed", 60},
+	{"gameplay.moveSpeed", 60},
+	{"gameplay.jumpVelocity", 60},
+	{"gameplay.gravity", 60 * 60},
+}
+
+// Config holds the user adjustable settings, loaded from config.yaml in the working directory
+// (or the file named by GAME_CONFIG) with environment overrides such as GAME_VIDEO_SCREENWIDTH
+type Config struct {
+	Version  int            `mapstructure:"version"`
+	Video    VideoConfig    `mapstructure:"video"`
+	Input    InputConfig    `mapstructure:"input"`
+	Gameplay GameplayConfig `mapstructure:"gameplay"`
+
+	v *viper.Viper
+}
+
+type VideoConfig struct {
+	ScreenWidth        int     `mapstructure:"screenWidth"`
+	ScreenHeight       int     `mapstructure:"screenHeight"`
+	RenderScale        float64 `mapstructure:"renderScale"`
+	FSR                float64 `mapstructure:"fsr"`
+	VSync              bool    `mapstructure:"vsync"`
+	OpenGL             bool    `mapstructure:"opengl"`
+	FOV                float64 `mapstructure:"fov"`
+	LightFalloff       float64 `mapstructure:"lightFalloff"`
+	GlobalIllumination float64 `mapstructure:"globalIllumination"`
+}
+
+type InputConfig struct {
+	TurnSpeed        float64 `mapstructure:"turnSpeed"` // radians per second
+	MouseMode        string  `mapstructure:"mouseMode"`
+	MouseSensitivity float64 `mapstructure:"mouseSensitivity"`
+	InvertY          bool    `mapstructure:"invertY"`
+}
+
+// GameplayConfig speeds are in map units per second, gravity in units per second squared
+type GameplayConfig struct {
+	MoveSpeed      float64 `mapstructure:"moveSpeed"`
+	SprintModifier float64 `mapstructure:"sprintModifier"`
+	JumpVelocity   float64 `mapstructure:"jumpVelocity"`
+	Gravity        float64 `mapstructure:"gravity"`
+	StartLevel     int     `mapstructure:"startLevel"`
+	TPS            int     `mapstructure:"tps"` // simulation ticks per second
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Version: configVersion,
+		Video: VideoConfig{
+			ScreenWidth:        800,
+			ScreenHeight:       600,
+			RenderScale:        0.5,
+			FSR:                4,
+			VSync:              false,
+			OpenGL:             false,
+			FOV:                68,
+			LightFalloff:       -300,
+			GlobalIllumination: 500,
+		},
+		Input: InputConfig{
+			TurnSpeed:        1.8,
+			MouseMode:        MouseModeLook.String(),
+			MouseSensitivity: 0.005,
+			InvertY:          false,
+		},
+		Gameplay: GameplayConfig{
+			MoveSpeed:      3.6,
+			SprintModifier: 2.0,
+			JumpVelocity:   4.8,
+			Gravity:        18,
+			StartLevel:     0,
+			TPS:            defaultTPS,
+		},
+	}
+}
+
+// values flattens the config into viper keys, used for both defaults and saving
+func (c *Config) values() map[string]interface{} {
+	return map[string]interface{}{
+		"version":                  c.Version,
+		"video.screenWidth":        c.Video.ScreenWidth,
+		"video.screenHeight":       c.Video.ScreenHeight,
+		"video.renderScale":        c.Video.RenderScale,
+		"video.fsr":                c.Video.FSR,
+		"video.vsync":              c.Video.VSync,
+		"video.opengl":             c.Video.OpenGL,
+		"video.fov":                c.Video.FOV,
+		"video.lightFalloff":       c.Video.LightFalloff,
+		"video.globalIllumination": c.Video.GlobalIllumination,
+		"input.turnSpeed":          c.Input.TurnSpeed,
+		"input.mouseMode":          c.Input.MouseMode,
+		"input.mouseSensitivity":   c.Input.MouseSensitivity,
+		"input.invertY":            c.Input.InvertY,
+		"gameplay.moveSpeed":       c.Gameplay.MoveSpeed,
+		"gameplay.sprintModifier":  c.Gameplay.SprintModifier,
+		"gameplay.jumpVelocity":    c.Gameplay.JumpVelocity,
+		"gameplay.gravity":         c.Gameplay.Gravity,
+		"gameplay.startLevel":      c.Gameplay.StartLevel,
+		"gameplay.tps":             c.Gameplay.TPS,
+	}
+}
+
+// loadConfig reads the config file if present, applies environment overrides and validates the result
+func loadConfig() (*Config, error) {
+	v := viper.New()
+	v.SetConfigType(configType)
+	if path := os.Getenv(envPrefix + "_CONFIG"); path != "" {
+		v.SetConfigFile(path)
+	} else {
+		v.SetConfigName(configName)
+		v.AddConfigPath(".")
+	}
+
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	for key, value := range defaultConfig().values() {
+		v.SetDefault(key, value)
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	} else if err := migrateConfig(v); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", v.ConfigFileUsed(), err)
+	}
+
+	c := &Config{v: v}
+	if err := v.Unmarshal(c); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return c, nil
+}
+
+// migrateConfig converts the settings read from an older config file to the current version.
+// Files from before versions were added are version 1. Values set from the environment are
+// taken as already being in the current units.
+func migrateConfig(v *viper.Viper) error {
+	version := 1
+	if v.InConfig("version") {
+		version = v.GetInt("version")
+	}
+	switch {
+	case version < 1:
+		return fmt.Errorf("invalid version %d", version)
+	case version > configVersion:
+		return fmt.Errorf("version %d is newer than this game supports (%d)", version, configVersion)
+	}
+
+	if version < 2 {
+		for _, s := range tickSettings {
+			env := envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+			if _, ok := os.LookupEnv(env); ok || !v.InConfig(s.key) {
+				continue
+			}
+			v.Set(s.key, v.GetFloat64(s.key)*s.scale)
+		}
+		log.Printf("config: converted per tick speeds in %s to per second", v.ConfigFileUsed())
+	}
+	v.Set("version", configVersion)
+	return nil
+}
+
+// Validate checks that all settings are within usable ranges
+func (c *Config) Validate() error {
+	switch {
+	case c.Video.ScreenWidth <= 0 || c.Video.ScreenHeight <= 0:
+		return fmt.Errorf("invalid resolution %dx%d", c.Video.ScreenWidth, c.Video.ScreenHeight)
+	case c.Video.RenderScale <= 0 || c.Video.RenderScale > 1:
+		return fmt.Errorf("renderScale %v must be greater than 0 and at most 1", c.Video.RenderScale)
+	case c.Video.FSR < 0:
+		return fmt.Errorf("fsr %v must not be negative", c.Video.FSR)
+	case c.Video.FOV <= 0 || c.Video.FOV >= 180:
+		return fmt.Errorf("fov %v must be between 0 and 180 degrees", c.Video.FOV)
+	case c.Video.GlobalIllumination < 0:
+		return fmt.Errorf("globalIllumination %v must not be negative", c.Video.GlobalIllumination)
+	case c.Input.TurnSpeed <= 0:
+		return fmt.Errorf("turnSpeed %v must be positive", c.Input.TurnSpeed)
+	case c.Input.MouseSensitivity <= 0:
+		return fmt.Errorf("mouseSensitivity %v must be positive", c.Input.MouseSensitivity)
+	case c.Gameplay.MoveSpeed <= 0:
+		return fmt.Errorf("moveSpeed %v must be positive", c.Gameplay.MoveSpeed)
+	case c.Gameplay.SprintModifier < 1:
+		return fmt.Errorf("sprintModifier %v must be at least 1", c.Gameplay.SprintModifier)
+	case c.Gameplay.JumpVelocity < 0:
+		return fmt.Errorf("jumpVelocity %v must not be negative", c.Gameplay.JumpVelocity)
+	case c.Gameplay.Gravity <= 0:
+		return fmt.Errorf("gravity %v must be positive", c.Gameplay.Gravity)
+	case c.Gameplay.StartLevel < 0:
+		return fmt.Errorf("startLevel %v must not be negative", c.Gameplay.StartLevel)
+	case c.Gameplay.TPS < minTPS:
+		return fmt.Errorf("tps %v must be at least %d", c.Gameplay.TPS, minTPS)
+	}
+	if _, err := parseMouseMode(c.Input.MouseMode); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Save writes the current settings back to the config file it was loaded from, or config.yaml
+func (c *Config) Save() error {
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	for key, value := range c.values() {
+		c.v.Set(key, value)
+	}
+
+	path := c.v.ConfigFileUsed()
+	if path == "" {
+		path = configName + "." + configType
+	}
+	if err := c.v.WriteConfigAs(path); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// configFile writes the yaml to a config file that loadConfig will read
+func configFile(t *testing.T, yaml string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(envPrefix+"_CONFIG", path)
+}
+
+func TestLoadConfigMigratesTickSpeeds(t *testing.T) {
+	configFile(t, `input:
+  turnSpeed: 0.03
+gameplay:
+  moveSpeed: 0.06
+  jumpVelocity: 0.08
+  gravity: 0.005
+  sprintModifier: 1.5
+`)
+	c, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const eps = 1e-9
+	for _, tt := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"turnSpeed", c.Input.TurnSpeed, 1.8},
+		{"moveSpeed", c.Gameplay.MoveSpeed, 3.6},
+		{"jumpVelocity", c.Gameplay.JumpVelocity, 4.8},
+		{"gravity", c.Gameplay.Gravity, 18},
+		{"sprintModifier", c.Gameplay.SprintModifier, 1.5},
+	} {
+		if d := tt.got - tt.want; d < -eps || d > eps {
+			t.Errorf("%s %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if c.Version != configVersion {
+		t.Errorf("version %d, want %d", c.Version, configVersion)
+	}
+}
+
+func TestLoadConfigVersion(t *testing.T) {
+	t.Run("current version is not converted", func(t *testing.T) {
+		configFile(t, "version: 2\ngameplay:\n  moveSpeed: 5\n")
+		c, err := loadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Gameplay.MoveSpeed != 5 {
+			t.Errorf("moveSpeed %v, want 5", c.Gameplay.MoveSpeed)
+		}
+	})
+
+	t.Run("environment is not converted", func(t *testing.T) {
+		configFile(t, "gameplay:\n  moveSpeed: 0.06\n")
+		t.Setenv(envPrefix+"_GAMEPLAY_MOVESPEED", "5")
+		c, err := loadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Gameplay.MoveSpeed != 5 {
+			t.Errorf("moveSpeed %v, want 5", c.Gameplay.MoveSpeed)
+		}
+	})
+
+	t.Run("defaults are not converted", func(t *testing.T) {
+		configFile(t, "video:\n  fov: 70\n")
+		c, err := loadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := defaultConfig().Gameplay.Gravity; c.Gameplay.Gravity != want {
+			t.Errorf("gravity %v, want the default %v", c.Gameplay.Gravity, want)
+		}
+	})
+
+	for _, version := range []string{"3", "0"} {
+		t.Run("rejects version "+version, func(t *testing.T) {
+			configFile(t, "version: "+version+"\n")
+			if _, err := loadConfig(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// seconds a door takes to slide fully open or closed
+	doorMoveTime = 0.5
+	// seconds an open door waits before closing by default
+	doorCloseDelay = 3.0
+)
+
+type doorState int
+
+const (
+	doorClosed doorState = iota
+	doorOpening
+	doorOpen
+	doorClosing
+)
+
+// Door is a wall cell on the ground level that slides open when used
+type Door struct {
+	X, Y       int
+	Texture    int     // wall texture number of the door cell
+	Key        string  // name of the key needed to open it, if locked
+	CloseDelay float64 // seconds to stay open before closing, 0 to stay open
+
+	state    doorState
+	amount   float64 // 0 when closed through 1 when fully open
+	openTime float64 // seconds the door has been fully open
+}
+
+// IsLocked returns true if the door needs a key to open
+func (d *Door) IsLocked() bool {
+	return d.Key != ""
+}
+
+// doorAt returns the door in the given cell, or nil
+func (m *Map) doorAt(x, y int) *Door {
+	return m.doors[mapCell{x: x, y: y}]
+}
+
+// setDoorSolid fills or clears the door cell in the ground wall level and rebuilds its collision lines
+func (m *Map) setDoorSolid(d *Door, solid bool) {
+	value := 0
+	if solid {
+		value = d.Texture
+	}
+	if m.wallMaps[0][d.X][d.Y] == value {
+		return
+	}
+	m.wallMaps[0][d.X][d.Y] = value
+	m.collisionMaps[0] = m.GetCollisionLines(0, .2)
+	m.grid.walls[0] = newLineIndex(m.collisionMaps[0])
+}
+
+// resetDoors closes every door in the map
+func (m *Map) resetDoors() {
+	for _, d := range m.doors {
+		d.state, d.amount, d.openTime = doorClosed, 0, 0
+		m.setDoorSolid(d, true)
+	}
+}
+
+// facingDoor returns the door the player is facing within use range, or nil
+func (g *Game) facingDoor() *Door {
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	pos := g.player.Position
+	dirX, dirY := math.Cos(g.player.Angle), math.Sin(g.player.Angle)
+
+	for d := 0.0; d <= interactRange; d += 0.05 {
+		x, y := pos.X+dirX*d, pos.Y+dirY*d
+		if !currentMap.inBounds(x, y) {
+			return nil
+		}
+		ix, iy := int(x), int(y)
+		if door := currentMap.doorAt(ix, iy); door != nil {
+			return door
+		}
+		if currentMap.isSolid(0, ix, iy) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// doorPrompt returns the use prompt for the door
+func (g *Game) doorPrompt(d *Door) string {
+	switch {
+	case d.IsLocked() && !g.player.Keys[d.Key]:
+		return fmt.Sprintf("Locked (needs %s key)", d.Key)
+	case d.state == doorOpen || d.state == doorOpening:
+		return "Close"
+	}
+	return "Open"
+}
+
+// useDoor opens or closes the door, if the player has the key for it
+func (g *Game) useDoor(d *Door) {
+	if d.IsLocked() && !g.player.Keys[d.Key] {
+		g.showMessage(fmt.Sprintf("You need the %s key", d.Key))
+		return
+	}
+
+	switch d.state {
+	case doorClosed, doorClosing:
+		d.state = doorOpening
+	case doorOpen, doorOpening:
+		g.closeDoor(d)
+	}
+}
+
+// closeDoor starts closing the door unless something is standing in the doorway
+func (g *Game) closeDoor(d *Door) {
+	if g.isDoorwayBlocked(d) {
+		return
+	}
+	d.state = doorClosing
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	currentMap.setDoorSolid(d, true)
+}
+
+// isDoorwayBlocked checks for the player or a sprite in the door cell, including the
+// collision clip distance around it
+func (g *Game) isDoorwayBlocked(d *Door) bool {
+	inDoorway := func(e *Entity) bool {
+		x, y := e.Position.X, e.Position.Y
+		return x > float64(d.X)-.2 && x < float64(d.X)+1.2 && y > float64(d.Y)-.2 && y < float64(d.Y)+1.2
+	}
+	if inDoorway(g.player.Entity) {
+		return true
+	}
+
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	for _, sprite := range currentMap.spritesNear(float64(d.X)-.2, float64(d.Y)-.2, float64(d.X)+1.2, float64(d.Y)+1.2) {
+		if sprite.CollisionRadius > 0 && inDoorway(sprite.Entity) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateDoors animates opening and closing doors and closes open doors when their timer runs out
+func (g *Game) updateDoors() {
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	for _, d := range currentMap.doors {
+		switch d.state {
+		case doorOpening:
+			d.amount += g.dt / doorMoveTime
+			if d.amount >= 1 {
+				d.amount = 1
+				d.state = doorOpen
+				d.openTime = 0
+				currentMap.setDoorSolid(d, false)
+			}
+		case doorOpen:
+			if d.CloseDelay > 0 {
+				d.openTime += g.dt
+				if d.openTime >= d.CloseDelay {
+					// keeps trying each tick until the doorway is clear
+					g.closeDoor(d)
+				}
+			}
+		case doorClosing:
+			d.amount -= g.dt / doorMoveTime
+			if d.amount <= 0 {
+				d.amount = 0
+				d.state = doorClosed
+			}
+		}
+		if d.state == doorOpening || d.state == doorClosing {
+			g.tex.updateDoorFrame(d)
+		}
+	}
+}
+
+// doorTexture returns the texture of the door slid aside by how far it is open
+func (t *TextureHandler) doorTexture(d *Door) *ebiten.Image {
+	if frame, ok := t.doorFrames[d]; ok && d.amount > 0 {
+		return frame.image
+	}
+	return t.wallTexture(d.Texture)
+}
+
+// updateDoorFrame redraws the cached texture of a moving door, done during the game update
+// so that the raycaster only reads it while rendering
+func (t *TextureHandler) updateDoorFrame(d *Door) {
+	frame, ok := t.doorFrames[d]
+	if !ok {
+		frame = &doorFrame{image: ebiten.NewImage(texWidth, texWidth), amount: -1}
+		t.doorFrames[d] = frame
+	}
+	if frame.amount == d.amount {
+		return
+	}
+
+	frame.image.Clear()
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(-d.amount*texWidth, 0)
+	frame.image.DrawImage(t.wallTexture(d.Texture), op)
+	frame.amount = d.amount
+}
+
+// doorFrame caches the rendered texture of a partly open door
+type doorFrame struct {
+	image  *ebiten.Image
+	amount float64
+}
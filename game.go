@@ -7,6 +7,7 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
@@ -15,6 +16,7 @@ import (
 )
 
 type Game struct {
+	cfg          *Config
 	tex          *TextureHandler
 	gameLevels   *gameLevels
 	camera       *raycaster.Camera
@@ -31,45 +33,127 @@ type Game struct {
 	height       int
 	minLightRGB  *color.NRGBA
 	maxLightRGB  *color.NRGBA
+
+	fovDegrees         float64
+	lightFalloff       float64
+	globalIllumination float64
+	floorTexture       *ebiten.Image
+	skyTexture         *ebiten.Image
+
+	mouseMode      MouseMode
+	mouseX, mouseY int
+	showAutomap    bool
+
+	weapon          *Weapon
+	damageEvents    []*DamageEvent
+	painTime        float64
+	playerDead      bool
+	levelStartArmor float64         // armor the player entered the current level with, restored on restart
+	levelStartKeys  map[string]bool // likewise for keys, as the level's key pickups are placed again
+	focused         *Sprite
+	focusedDoor     *Door
+	hudMessage      string
+	hudMessageTime  float64
+
+	scenes sceneStack
+	quit   bool
+
+	dt             float64 // seconds simulated by each tick
+	prevView, view playerView
+	lastTick       time.Time
 }
 
 func NewGame() *Game {
 	fmt.Println("Creating game")
 	g := new(Game)
-	ebiten.SetWindowTitle("Game file")
-	g.fsr = 4
-	g.screenHeight = 600
-	g.screenWidth = 800
-	g.renderScale = 0.5
+	ebiten.SetWindowTitle(gameTitle)
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	g.cfg = cfg
+	g.fsr = cfg.Video.FSR
+	g.screenHeight = cfg.Video.ScreenHeight
+	g.screenWidth = cfg.Video.ScreenWidth
+	g.renderScale = cfg.Video.RenderScale
+	g.vsync = cfg.Video.VSync
+	g.opengl = cfg.Video.OpenGL
 	if g.opengl {
 		os.Setenv("EBITENGINE_GRAPHICS_LIBRARY", "opengl")
 	}
 	g.setResolution(g.screenWidth, g.screenHeight)
 	g.setRenderScale(g.renderScale)
 	g.setVsyncEnabled(g.vsync)
+	g.setTPS(cfg.Gameplay.TPS)
 	g.gameLevels = loadGameLevels()
-	g.tex = NewTextureHandler(g.gameLevels)
-	angleDegrees := 60.0
-	g.player = NewPlayer(1.5, 1.5, geom.Radians(angleDegrees), 0)
-	g.player.CollisionRadius = 0.2
-	g.player.CollisionHeight = 0.5
-	g.camera = raycaster.NewCamera(g.width, g.height, texWidth, g.gameLevels.levelMaps[g.gameLevels.currentLevel], g.tex)
-	g.camera.SetFloorTexture(getTextureFromFile("sky.png"))
-	g.camera.SetSkyTexture(getTextureFromFile("sky.png"))
-	// initialize camera to player position
-	g.updatePlayerCamera(true)
-	g.camera.SetFovAngle(68, 1.0)
-	g.setLightFalloff(-300)
-	g.setGlobalIllumination(500)
-	minLightRGB := &color.NRGBA{R: 15, G: 15, B: 15, A: 255}
-	maxLightRGB := &color.NRGBA{R: 255, G: 255, B: 255, A: 255}
-	g.setLightRGB(minLightRGB, maxLightRGB)
+	if cfg.Gameplay.StartLevel >= len(g.gameLevels.levelMaps) {
+		log.Fatalf("config: startLevel %d does not exist", cfg.Gameplay.StartLevel)
+	}
+	g.gameLevels.currentLevel = cfg.Gameplay.StartLevel
+	tex, err := NewTextureHandler(g.gameLevels)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g.tex = tex
+	g.player = newSpawnedPlayer(g.gameLevels.levelMaps[g.gameLevels.currentLevel])
+	g.floorTexture = getTextureFromFile("floor.png")
+	g.skyTexture = getTextureFromFile("sky.png")
+	g.fovDegrees = cfg.Video.FOV
+	g.lightFalloff = cfg.Video.LightFalloff
+	g.globalIllumination = cfg.Video.GlobalIllumination
+	g.minLightRGB = &color.NRGBA{R: 15, G: 15, B: 15, A: 255}
+	g.maxLightRGB = &color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	g.initCamera()
+	mouseMode, _ := parseMouseMode(cfg.Input.MouseMode)
+	g.setMouseMode(mouseMode)
 	img, _, _ := ebitenutil.NewImageFromFile("./resources/headshot.png")
 	g.headshot = img
+	g.weapon = NewRevolver()
+	g.scenes.push(newTitleScene(g))
 
 	return (g)
 }
 
+// newGame starts over from the configured start level with a new player
+func (g *Game) newGame() {
+	for _, m := range g.gameLevels.levelMaps {
+		m.resetDoors()
+		m.seen = nil
+	}
+	g.gameLevels.currentLevel = g.cfg.Gameplay.StartLevel
+	g.player = newSpawnedPlayer(g.gameLevels.levelMaps[g.gameLevels.currentLevel])
+	g.markLevelStart()
+	g.restartLevel()
+}
+
+// initCamera creates the camera for the current level and applies the game's view settings
+func (g *Game) initCamera() {
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	g.camera = raycaster.NewCamera(g.width, g.height, texWidth, currentMap, g.tex)
+	g.camera.SetFloorTexture(g.floorTexture)
+	g.camera.SetSkyTexture(g.skyTexture)
+	// initialize camera to player position
+	g.updatePlayerCamera(true)
+	g.setFovAngle(g.fovDegrees)
+	g.setLightFalloff(g.lightFalloff)
+	g.setGlobalIllumination(g.globalIllumination)
+	g.setLightRGB(g.minLightRGB, g.maxLightRGB)
+}
+
+// saveConfig writes the game's current video settings back to the config file
+func (g *Game) saveConfig() error {
+	g.cfg.Video.ScreenWidth = g.screenWidth
+	g.cfg.Video.ScreenHeight = g.screenHeight
+	g.cfg.Video.RenderScale = g.renderScale
+	g.cfg.Video.FSR = g.fsr
+	g.cfg.Video.VSync = g.vsync
+	g.cfg.Video.OpenGL = g.opengl
+	g.cfg.Video.FOV = g.fovDegrees
+	g.cfg.Video.LightFalloff = g.lightFalloff
+	g.cfg.Video.GlobalIllumination = g.globalIllumination
+	return g.cfg.Save()
+}
+
 func (g *Game) Run() {
 	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
@@ -77,18 +161,40 @@ func (g *Game) Run() {
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	w, h := g.screenWidth, g.screenHeight
-	return w, h
+	return g.scenes.Layout(outsideWidth, outsideHeight)
 }
 func (g *Game) Update() error {
-	g.handleInput()
+	return g.scenes.Update(g.dt)
+}
+func (g *Game) Draw(screen *ebiten.Image) {
+	g.scenes.Draw(screen)
+}
+
+// updateGameplay advances the game world by one tick
+func (g *Game) updateGameplay() {
+	if !g.playerDead {
+		g.focused = g.focusedSprite()
+		g.focusedDoor = nil
+		if g.focused == nil {
+			g.focusedDoor = g.facingDoor()
+		}
+		g.handleInput()
+		g.updatePlayerZ()
+		g.checkLevelExit()
+		g.weapon.Update(g.dt)
+	}
+	g.updateDoors()
 	g.updateSprites()
+	g.processDamage()
+	g.updateAutomap()
+	g.updateHUDMessage()
 
 	// handle player camera movement
 	g.updatePlayerCamera(false)
-	return nil
 }
-func (g *Game) Draw(screen *ebiten.Image) {
+
+// drawGameplay renders the view of the game world and the HUD
+func (g *Game) drawGameplay(screen *ebiten.Image) {
 	sprites := g.gameLevels.levelMaps[g.gameLevels.currentLevel].sprites
 	numSprites := len(sprites)
 	raycastSprites := make([]raycaster.Sprite, numSprites)
@@ -97,6 +203,7 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		raycastSprites[index] = sprite
 		index += 1
 	}
+	g.setCameraView(g.interpolatedView())
 	g.camera.Update(raycastSprites)
 	g.camera.Draw(g.scene)
 	op := &ebiten.DrawImageOptions{}
@@ -105,17 +212,16 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		op.GeoM.Scale(1/g.renderScale, 1/g.renderScale)
 	}
 	screen.DrawImage(g.scene, op)
-	op = &ebiten.DrawImageOptions{}
-	op.GeoM.Scale(.15, .15)
-	op.GeoM.Translate(0, 600-(float64(g.headshot.Bounds().Dy())*.15))
-	screen.DrawImage(g.headshot, op)
-	op2 := &ebiten.DrawImageOptions{}
-	img, _, _ := ebitenutil.NewImageFromFile("./resources/revolver.png")
-	op2.GeoM.Scale(2, 2)
-	op2.GeoM.Translate(800-(float64(img.Bounds().Dx())*2), 600-(float64(img.Bounds().Dy())*2))
 
-	screen.DrawImage(img, op2)
+	g.drawPortrait(screen)
+	g.drawWeapon(screen)
+	g.drawInteraction(screen)
 
+	if g.showAutomap {
+		g.drawAutomap(screen)
+	} else {
+		g.drawMinimap(screen)
+	}
 }
 
 func (g *Game) setResolution(screenWidth, screenHeight int) {
@@ -134,27 +240,31 @@ func (g *Game) setRenderScale(renderScale float64) {
 	g.scene = ebiten.NewImage(g.width, g.height)
 }
 
+// updatePlayerCamera records the player's view at the end of a tick. Frames are drawn from a view
+// between this and the previous tick's, unless forceUpdate is set, for when the player is put
+// somewhere new rather than moving there, which jumps the camera straight to the new view.
 func (g *Game) updatePlayerCamera(forceUpdate bool) {
-	if !g.player.Moved && !forceUpdate {
-		// only update camera position if player moved or forceUpdate set
-		return
-	}
-
-	// reset player moved flag to only update camera when necessary
 	g.player.Moved = false
 
-	g.camera.SetPosition(g.player.Position.Copy())
-	g.camera.SetPositionZ(g.player.CameraZ)
-	g.camera.SetHeadingAngle(g.player.Angle)
+	g.prevView = g.view
+	g.view = g.currentPlayerView()
+	if forceUpdate {
+		g.prevView = g.view
+	}
+	g.lastTick = time.Now()
+	g.setCameraView(g.view)
 }
 
 func (g *Game) setFovAngle(fovDegrees float64) {
+	g.fovDegrees = fovDegrees
 	g.camera.SetFovAngle(fovDegrees, 1.0)
 }
 func (g *Game) setLightFalloff(lightFalloff float64) {
+	g.lightFalloff = lightFalloff
 	g.camera.SetLightFalloff(lightFalloff)
 }
 func (g *Game) setGlobalIllumination(globalIllumination float64) {
+	g.globalIllumination = globalIllumination
 	g.camera.SetGlobalIllumination(globalIllumination)
 }
 
@@ -175,6 +285,11 @@ func (g *Game) Rotate(rSpeed float64) {
 
 	g.player.Moved = true
 }
+func (g *Game) Pitch(pSpeed float64) {
+	// current raycasting method can only allow up to 22.5 degrees down, 45 degrees up
+	g.player.Pitch = geom.Clamp(pSpeed+g.player.Pitch, -math.Pi/8, math.Pi/4)
+	g.player.Moved = true
+}
 func (g *Game) Move(mSpeed float64) {
 	moveLine := geom.LineFromAngle(g.player.Position.X, g.player.Position.Y, g.player.Angle, mSpeed)
 
@@ -184,31 +299,69 @@ func (g *Game) Move(mSpeed float64) {
 		g.player.Moved = true
 	}
 }
+func (g *Game) Strafe(sSpeed float64) {
+	// positive speed strafes to the right of the heading angle
+	strafeAngle := g.player.Angle - geom.HalfPi
+	moveLine := geom.LineFromAngle(g.player.Position.X, g.player.Position.Y, strafeAngle, sSpeed)
+
+	newPos, _, _ := g.getValidMove(g.player.Entity, moveLine.X2, moveLine.Y2, g.player.PositionZ, true)
+	if !newPos.Equals(g.player.Pos()) {
+		g.player.Position = newPos
+		g.player.Moved = true
+	}
+}
+func (g *Game) Crouch(crouch bool) {
+	if crouch == g.player.Crouching {
+		return
+	}
+	if !crouch && !g.canStand() {
+		// something is overhead, stay crouched
+		return
+	}
+
+	g.player.Crouching = crouch
+	if crouch {
+		g.player.CameraZ = playerCrouchCameraZ
+		g.player.CollisionHeight = playerCrouchHeight
+	} else {
+		g.player.CameraZ = playerStandCameraZ
+		g.player.CollisionHeight = playerStandHeight
+	}
+	g.player.Moved = true
+}
 func (g *Game) setVsyncEnabled(enableVsync bool) {
 	g.vsync = enableVsync
 	ebiten.SetVsyncEnabled(enableVsync)
 }
 func (g *Game) updateSprites() {
 	// Testing animated sprite movement
-	sprites := g.gameLevels.levelMaps[g.gameLevels.currentLevel].sprites
-	for s := range sprites {
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	for s := range currentMap.sprites {
+		if s.AI != nil && !s.IsDead() {
+			g.updateAI(s)
+		}
 		if s.Velocity != 0 {
-			vLine := geom.LineFromAngle(s.Position.X, s.Position.Y, s.Angle, s.Velocity)
+			vLine := geom.LineFromAngle(s.Position.X, s.Position.Y, s.Angle, s.Velocity*g.dt)
 
 			xCheck := vLine.X2
 			yCheck := vLine.Y2
 			zCheck := s.PositionZ
 
 			newPos, isCollision, _ := g.getValidMove(s.Entity, xCheck, yCheck, zCheck, false)
-			if isCollision {
+			if isCollision && s.AI != nil {
+				// blocked by something not on the grid such as another sprite, find a new path
+				s.AI.path = nil
+				s.Velocity = 0
+			} else if isCollision {
 				// for testing purposes, letting the sample sprite ping pong off walls in somewhat random direction
 				s.Angle = randFloat(-math.Pi, math.Pi)
-				s.Velocity = randFloat(0.01, 0.03)
+				s.Velocity = randFloat(0.6, 1.8)
 			} else {
-				s.Position = newPos
+				currentMap.moveSprite(s, newPos)
 			}
 		}
-		s.Update(g.player.Position)
+		s.playMovementClip()
+		s.Update(g.player.Position, g.dt)
 	}
 }
 func randFloat(min, max float64) float64 {
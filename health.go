@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"maps"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/harbdog/raycaster-go"
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+const (
+	// fraction of damage taken by armor instead of health while armor remains
+	armorAbsorption = 1.0 / 3
+
+	playerMaxHealth = 100
+	painTime        = 1.0 / 3 // seconds the portrait shows pain after being hit
+	corpseHeight    = 0.35
+)
+
+// DamageEvent records damage dealt to an entity, queued until processed by the game update
+type DamageEvent struct {
+	Target *Entity
+	Source *Entity
+	Amount float64
+
+	// filled in once the damage has been applied
+	HealthDamage float64
+	ArmorDamage  float64
+	Killed       bool
+}
+
+// IsDamageable returns true if the entity has a health value
+func (e *Entity) IsDamageable() bool {
+	return e.MaxHealth > 0
+}
+
+// IsDead returns true if the entity has health and it has run out
+func (e *Entity) IsDead() bool {
+	return e.IsDamageable() && e.Health <= 0
+}
+
+// TakeDamage reduces armor and health by the given amount, returning how much each was reduced
+func (e *Entity) TakeDamage(amount float64) (healthDamage, armorDamage float64) {
+	if !e.IsDamageable() || e.IsDead() || amount <= 0 {
+		return 0, 0
+	}
+
+	armorDamage = math.Min(amount*armorAbsorption, e.Armor)
+	healthDamage = math.Min(amount-armorDamage, e.Health)
+
+	e.Armor -= armorDamage
+	e.Health -= healthDamage
+	return healthDamage, armorDamage
+}
+
+// damage queues damage to the target entity from the (optional) source
+func (g *Game) damage(target, source *Entity, amount float64) {
+	if target == nil || !target.IsDamageable() {
+		return
+	}
+	g.damageEvents = append(g.damageEvents, &DamageEvent{Target: target, Source: source, Amount: amount})
+}
+
+// processDamage applies queued damage events and handles any resulting deaths
+func (g *Game) processDamage() {
+	events := g.damageEvents
+	g.damageEvents = nil
+
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	for _, ev := range events {
+		if ev.Target.IsDead() {
+			continue
+		}
+		ev.HealthDamage, ev.ArmorDamage = ev.Target.TakeDamage(ev.Amount)
+		ev.Killed = ev.Target.IsDead()
+
+		if ev.Target == g.player.Entity {
+			g.painTime = painTime
+			if ev.Killed {
+				g.killPlayer()
+			}
+			continue
+		}
+
+		if ev.Killed {
+			for sprite := range currentMap.sprites {
+				if sprite.Entity == ev.Target {
+					g.killSprite(sprite)
+					break
+				}
+			}
+		}
+	}
+
+	g.painTime = math.Max(g.painTime-g.dt, 0)
+}
+
+// killSprite plays a dead sprite's die clip and leaves it where it fell, or if it has no die
+// clip removes it from the level and leaves a corpse in its place
+func (g *Game) killSprite(s *Sprite) {
+	if s.HasClip("die") {
+		s.Velocity = 0
+		s.CollisionRadius, s.CollisionHeight = 0, 0
+		s.Focusable = false
+		s.PlayClip("die")
+		// the body stays down once it has fallen, even if the clip was given to loop
+		s.OnClipDone = func(s *Sprite, clip string) {
+			if clip == "die" {
+				s.EndClip()
+			}
+		}
+		return
+	}
+
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	currentMap.removeSprite(s)
+	currentMap.addSprite(newCorpseSprite(s))
+}
+
+// newCorpseSprite creates a flattened, darkened copy of the sprite's current frame with no collision
+func newCorpseSprite(s *Sprite) *Sprite {
+	tex := s.Texture()
+	w, h := tex.Bounds().Dx(), tex.Bounds().Dy()
+
+	img := ebiten.NewImage(w, h)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(1, corpseHeight)
+	op.GeoM.Translate(0, float64(h)*(1-corpseHeight))
+	op.ColorScale.Scale(0.5, 0.2, 0.2, 1)
+	img.DrawImage(tex, op)
+
+	mapColor := color.RGBA{R: s.MapColor.R / 2, G: s.MapColor.G / 2, B: s.MapColor.B / 2, A: s.MapColor.A}
+	corpse := NewSprite(s.Position.X, s.Position.Y, s.Entity.Scale, img, mapColor, raycaster.AnchorBottom, 0, 0)
+	corpse.PositionZ = s.PositionZ
+	corpse.Focusable = false
+	return corpse
+}
+
+func (g *Game) killPlayer() {
+	g.playerDead = true
+	g.player.VelocityZ = 0
+	g.scenes.push(newGameOverScene(g))
+}
+
+// markLevelStart remembers the armor and keys the player enters a level with
+func (g *Game) markLevelStart() {
+	g.levelStartArmor = g.player.Armor
+	g.levelStartKeys = maps.Clone(g.player.Keys)
+}
+
+// restartLevel restores the player's health, and the armor and keys they entered the level
+// with, then reloads the current level from its spawn
+func (g *Game) restartLevel() {
+	g.playerDead = false
+	g.painTime = 0
+	g.damageEvents = nil
+	g.hudMessage, g.hudMessageTime = "", 0
+
+	g.player.Health = g.player.MaxHealth
+	g.player.Armor = g.levelStartArmor
+	g.player.Keys = maps.Clone(g.levelStartKeys)
+	g.player.PositionZ, g.player.VelocityZ, g.player.Pitch = 0, 0, 0
+	g.weapon = NewRevolver()
+
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	currentMap.resetDoors()
+	g.changeLevel(&levelExit{level: g.gameLevels.currentLevel, dest: currentMap.spawn, destAngle: currentMap.spawnAngle})
+}
+
+// drawPortrait draws the player headshot, tinted to reflect health Doom-style
+func (g *Game) drawPortrait(screen *ebiten.Image) {
+	scale := .15
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+
+	healthRatio := 1.0
+	if g.player.IsDamageable() {
+		healthRatio = geom.Clamp(g.player.Health/g.player.MaxHealth, 0, 1)
+	}
+
+	switch {
+	case g.playerDead:
+		// slumped over and drained of color
+		h := float64(g.headshot.Bounds().Dy()) * scale
+		op.GeoM.Translate(0, -h)
+		op.GeoM.Rotate(math.Pi / 12)
+		op.GeoM.Translate(0, h)
+		op.ColorScale.Scale(0.3, 0.1, 0.1, 1)
+	case g.painTime > 0:
+		op.ColorScale.Scale(1, 0.4, 0.4, 1)
+	default:
+		// bleed out to red as health drops
+		op.ColorScale.Scale(1, float32(0.5+0.5*healthRatio), float32(0.5+0.5*healthRatio), 1)
+	}
+
+	portraitW, portraitH := float64(g.headshot.Bounds().Dx())*scale, float64(g.headshot.Bounds().Dy())*scale
+	op.GeoM.Translate(0, float64(g.screenHeight)-portraitH)
+	screen.DrawImage(g.headshot, op)
+
+	status := fmt.Sprintf("HP %.0f  AR %.0f", math.Max(g.player.Health, 0), g.player.Armor)
+	ebitenutil.DebugPrintAt(screen, status, int(portraitW)+10, g.screenHeight-20)
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"math"
+
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 type MouseMode int
@@ -12,24 +16,154 @@ const (
 	MouseModeCursor
 )
 
+func (m MouseMode) String() string {
+	switch m {
+	case MouseModeLook:
+		return "look"
+	case MouseModeMove:
+		return "move"
+	case MouseModeCursor:
+		return "cursor"
+	}
+	return fmt.Sprintf("MouseMode(%d)", int(m))
+}
+
+func parseMouseMode(s string) (MouseMode, error) {
+	for _, m := range []MouseMode{MouseModeLook, MouseModeMove, MouseModeCursor} {
+		if s == m.String() {
+			return m, nil
+		}
+	}
+	return MouseModeLook, fmt.Errorf("unknown mouse mode %q", s)
+}
+
+// setMouseMode captures the cursor for look and move modes, and frees it for cursor mode
+func (g *Game) setMouseMode(mode MouseMode) {
+	if mode == MouseModeCursor {
+		ebiten.SetCursorMode(ebiten.CursorModeVisible)
+	} else {
+		ebiten.SetCursorMode(ebiten.CursorModeCaptured)
+	}
+	g.mouseMode = mode
+
+	// reset last position so the first delta after switching is ignored
+	g.mouseX, g.mouseY = math.MinInt32, math.MinInt32
+}
+
+// mouseDelta returns how far the cursor moved since the previous update
+func (g *Game) mouseDelta() (int, int) {
+	x, y := ebiten.CursorPosition()
+	if g.mouseX == math.MinInt32 && g.mouseY == math.MinInt32 {
+		// the first reading only establishes where the cursor is, wherever that is
+		g.mouseX, g.mouseY = x, y
+		return 0, 0
+	}
+
+	dx, dy := g.mouseX-x, g.mouseY-y
+	g.mouseX, g.mouseY = x, y
+	return dx, dy
+}
+
 func (g *Game) handleInput() {
 
 	forward := false
 	backward := false
 	rotLeft := false
 	rotRight := false
+	strafeLeft := false
+	strafeRight := false
+
+	g.Crouch(ebiten.IsKeyPressed(ebiten.KeyC))
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		g.Jump()
+	}
 
 	moveModifier := 1.0
-	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+	if g.player.Crouching {
+		moveModifier = playerCrouchMoveFactor
+	} else if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		moveModifier = g.cfg.Gameplay.SprintModifier
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		g.useFocused()
+	}
+	if g.mouseMode != MouseModeCursor && inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		g.fireWeapon()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		g.weapon.Reload()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		g.showAutomap = !g.showAutomap
+	}
+
+	// M toggles between mouse look and mouse move, holding Control frees the cursor
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) {
+		if g.cfg.Input.MouseMode == MouseModeLook.String() {
+			g.cfg.Input.MouseMode = MouseModeMove.String()
+		} else {
+			g.cfg.Input.MouseMode = MouseModeLook.String()
+		}
+	}
+	mouseMode, _ := parseMouseMode(g.cfg.Input.MouseMode)
+	if ebiten.IsKeyPressed(ebiten.KeyControl) {
+		mouseMode = MouseModeCursor
+	}
+	if mouseMode != g.mouseMode {
+		g.setMouseMode(mouseMode)
+	}
 
+	sensitivity := g.cfg.Input.MouseSensitivity
+	switch g.mouseMode {
+	case MouseModeMove:
+		dx, dy := g.mouseDelta()
+		if dx != 0 {
+			g.Rotate(sensitivity * float64(dx))
+		}
+		if dy != 0 {
+			g.Move(2 * sensitivity * float64(dy))
+		}
+	case MouseModeLook:
+		dx, dy := g.mouseDelta()
+		if g.cfg.Input.InvertY {
+			dy = -dy
+		}
+		if dx != 0 {
+			g.Rotate(sensitivity * float64(dx))
+		}
+		if dy != 0 {
+			g.Pitch(sensitivity * float64(dy))
+		}
 	}
 
-	if ebiten.IsKeyPressed(ebiten.KeyA) || ebiten.IsKeyPressed(ebiten.KeyLeft) {
+	// A/D strafe while the mouse is turning the view, otherwise they rotate
+	if ebiten.IsKeyPressed(ebiten.KeyA) {
+		if g.mouseMode == MouseModeCursor {
+			rotLeft = true
+		} else {
+			strafeLeft = true
+		}
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyD) {
+		if g.mouseMode == MouseModeCursor {
+			rotRight = true
+		} else {
+			strafeRight = true
+		}
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyLeft) {
 		rotLeft = true
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyD) || ebiten.IsKeyPressed(ebiten.KeyRight) {
+	if ebiten.IsKeyPressed(ebiten.KeyRight) {
 		rotRight = true
 	}
+	if ebiten.IsKeyPressed(ebiten.KeyQ) {
+		strafeLeft = true
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyE) {
+		strafeRight = true
+	}
 
 	if ebiten.IsKeyPressed(ebiten.KeyW) || ebiten.IsKeyPressed(ebiten.KeyUp) {
 		forward = true
@@ -38,15 +172,23 @@ func (g *Game) handleInput() {
 		backward = true
 	}
 
+	moveSpeed := g.cfg.Gameplay.MoveSpeed * g.dt
+	turnSpeed := g.cfg.Input.TurnSpeed * g.dt
+
 	if forward {
-		g.Move(0.06 * moveModifier)
+		g.Move(moveSpeed * moveModifier)
 	} else if backward {
-		g.Move(-0.06 * moveModifier)
+		g.Move(-moveSpeed * moveModifier)
+	}
+	if strafeLeft {
+		g.Strafe(-moveSpeed * moveModifier)
+	} else if strafeRight {
+		g.Strafe(moveSpeed * moveModifier)
 	}
 	if rotLeft {
-		g.Rotate(0.03 * moveModifier)
+		g.Rotate(turnSpeed * moveModifier)
 	} else if rotRight {
-		g.Rotate(-0.03 * moveModifier)
+		g.Rotate(-turnSpeed * moveModifier)
 	}
 
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+const (
+	// maximum distance from the player a sprite can be used
+	interactRange = 1.5
+	// how far from the screen center (in render pixels) a sprite's screen rect may be to still be focused
+	interactScreenTolerance = 8
+
+	// seconds a HUD message stays on screen
+	hudMessageTime = 2.0
+)
+
+// InteractFunc is called when the player uses a sprite
+type InteractFunc func(g *Game, s *Sprite)
+
+// Interaction is an action the player can perform on a focused sprite
+type Interaction struct {
+	Name   string
+	Prompt string
+	Action InteractFunc
+}
+
+// interactions holds the named interactions available to map files
+var interactions = map[string]*Interaction{}
+
+func registerInteraction(name, prompt string, action InteractFunc) {
+	interactions[name] = &Interaction{Name: name, Prompt: prompt, Action: action}
+}
+
+func init() {
+	registerInteraction("pickup", "Pick up", func(g *Game, s *Sprite) {
+		currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+		currentMap.removeSprite(s)
+		if s.Key != "" {
+			g.player.Keys[s.Key] = true
+			g.showMessage(fmt.Sprintf("Picked up the %s key", s.Key))
+			return
+		}
+		g.showMessage("Picked up")
+	})
+	registerInteraction("talk", "Talk", func(g *Game, s *Sprite) {
+		g.showMessage("...")
+	})
+}
+
+// focusedSprite returns the usable sprite within range nearest to the center of the view, or nil
+func (g *Game) focusedSprite() *Sprite {
+	center := image.Pt(g.width/2, g.height/2)
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+
+	var focused *Sprite
+	focusedScreenDist, focusedDist := math.MaxFloat64, math.MaxFloat64
+	for _, sprite := range currentMap.spritesNearCircle(g.player.Position, interactRange) {
+		if !sprite.IsFocusable() || sprite.Interaction == nil {
+			continue
+		}
+		rect := sprite.ScreenRect()
+		if rect == nil {
+			// not currently rendered on screen
+			continue
+		}
+
+		dist := geom.Distance(g.player.Position.X, g.player.Position.Y, sprite.Position.X, sprite.Position.Y)
+		if dist > interactRange {
+			continue
+		}
+
+		screenDist := rectDistance(*rect, center)
+		if screenDist > interactScreenTolerance {
+			continue
+		}
+		if !currentMap.hasLineOfSight(g.player.Position, g.player.PositionZ+g.player.CameraZ, sprite.Position, sprite.centerZ()) {
+			continue
+		}
+
+		if screenDist < focusedScreenDist || (screenDist == focusedScreenDist && dist < focusedDist) {
+			focused, focusedScreenDist, focusedDist = sprite, screenDist, dist
+		}
+	}
+
+	return focused
+}
+
+// rectDistance returns the distance from a point to the nearest edge of the rectangle, 0 if inside
+func rectDistance(r image.Rectangle, p image.Point) float64 {
+	dx := math.Max(math.Max(float64(r.Min.X-p.X), 0), float64(p.X-r.Max.X))
+	dy := math.Max(math.Max(float64(r.Min.Y-p.Y), 0), float64(p.Y-r.Max.Y))
+	return math.Hypot(dx, dy)
+}
+
+// useFocused performs the interaction of the sprite under the crosshair, or uses the door being faced
+func (g *Game) useFocused() {
+	if g.focused == nil || g.focused.Interaction == nil {
+		if g.focusedDoor != nil {
+			g.useDoor(g.focusedDoor)
+		}
+		return
+	}
+	g.focused.Interaction.Action(g, g.focused)
+	g.focused = nil
+}
+
+func (g *Game) showMessage(msg string) {
+	g.hudMessage = msg
+	g.hudMessageTime = hudMessageTime
+}
+
+func (g *Game) updateHUDMessage() {
+	if g.hudMessageTime > 0 {
+		g.hudMessageTime -= g.dt
+		if g.hudMessageTime <= 0 {
+			g.hudMessage, g.hudMessageTime = "", 0
+		}
+	}
+}
+
+// drawInteraction draws the use prompt for the focused sprite or door and any recent message
+func (g *Game) drawInteraction(screen *ebiten.Image) {
+	cx, cy := g.screenWidth/2, g.screenHeight/2
+	prompt := ""
+	switch {
+	case g.focused != nil:
+		prompt = fmt.Sprintf("[F] %s", g.focused.Interaction.Prompt)
+	case g.focusedDoor != nil:
+		prompt = fmt.Sprintf("[F] %s", g.doorPrompt(g.focusedDoor))
+	}
+	if prompt != "" {
+		ebitenutil.DebugPrintAt(screen, prompt, cx-len(prompt)*3, cy+24)
+	}
+	if g.hudMessage != "" {
+		ebitenutil.DebugPrintAt(screen, g.hudMessage, cx-len(g.hudMessage)*3, cy+48)
+	}
+}
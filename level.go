@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+type mapCell struct {
+	x, y int
+}
+
+// levelExit is a trigger cell that moves the player to another level
+type levelExit struct {
+	level     int
+	dest      *geom.Vector2
+	destAngle float64
+}
+
+// exitAt returns the level exit at the given map position, or nil if there is none
+func (m *Map) exitAt(x, y float64) *levelExit {
+	if !m.inBounds(x, y) {
+		return nil
+	}
+	return m.exits[mapCell{x: int(x), y: int(y)}]
+}
+
+// validateExits checks that every exit leads to a loaded level and lands outside of walls and exits
+func (gl *gameLevels) validateExits() error {
+	for level, m := range gl.levelMaps {
+		for cell, e := range m.exits {
+			if e.level < 0 || e.level >= len(gl.levelMaps) {
+				return fmt.Errorf("level%d exit (%d,%d): level %d does not exist", level, cell.x, cell.y, e.level)
+			}
+			destMap := gl.levelMaps[e.level]
+			dest := destMap.spawn
+			if e.dest != nil {
+				dest = e.dest
+			}
+			if !destMap.inBounds(dest.X, dest.Y) || destMap.isSolid(0, int(dest.X), int(dest.Y)) {
+				return fmt.Errorf("level%d exit (%d,%d): destination (%v,%v) is not open floor", level, cell.x, cell.y, dest.X, dest.Y)
+			}
+			if destMap.exitAt(dest.X, dest.Y) != nil {
+				return fmt.Errorf("level%d exit (%d,%d): destination (%v,%v) is another exit", level, cell.x, cell.y, dest.X, dest.Y)
+			}
+		}
+	}
+	return nil
+}
+
+// checkLevelExit changes level when the player has moved onto an exit cell
+func (g *Game) checkLevelExit() {
+	if !g.player.Moved {
+		return
+	}
+
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	if e := currentMap.exitAt(g.player.Position.X, g.player.Position.Y); e != nil {
+		g.changeLevel(e)
+	}
+}
+
+// changeLevel switches to the exit's level, reloading its sprites and placing the player at the destination
+func (g *Game) changeLevel(e *levelExit) {
+	g.gameLevels.currentLevel = e.level
+	destMap := g.gameLevels.levelMaps[e.level]
+
+	dest, destAngle := destMap.spawn, destMap.spawnAngle
+	if e.dest != nil {
+		dest, destAngle = e.dest, e.destAngle
+	}
+	g.player.Position = dest.Copy()
+	g.player.Angle = destAngle
+	g.markLevelStart()
+
+	g.tex.loadSprites()
+
+	// camera needs to be recreated to reference the new map
+	g.initCamera()
+}
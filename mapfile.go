@@ -0,0 +1,605 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// Map files are plain text and are read line by line. Blank lines are ignored and
+// anything following a '#' is treated as a comment. The following directives are supported:
+//
+//	spawn <x> <y> <angleDegrees>
+//	    player starting position and heading
+//
+//	walls
+//	<rows>
+//	end
+//	    a grid of wall texture numbers for one Z-level, repeat the block for each level
+//	    from the ground up
+//
+//	floor [<z>]
+//	<rows>
+//	end
+//	    a grid of floor texture numbers for Z-level z (default 0), optional, cells with
+//	    texture 0 show the static floor texture
+//
+//	ceiling [<z>]
+//	<rows>
+//	end
+//	    a grid of ceiling texture numbers for Z-level z (default 0), optional, cells with
+//	    texture 0 are open to the sky
+//
+//	mid [<z>]
+//	<rows>
+//	end
+//	    a grid of mid texture numbers for Z-level z (default 0), optional, drawn in open
+//	    cells and see-through where the texture is transparent
+//
+//	walltexture <n> <name>
+//	flattexture <n> <name>
+//	    uses the named wall or flat (floor and ceiling) texture from the texture manifest
+//	    for cells numbered n in the walls, or floor and ceiling grids
+//
+//	midtexture <n> <name> [passable] [opaque]
+//	    uses the named mid texture for cells numbered n in the mid grids, by default these
+//	    cells block movement but not line of sight, passable lets entities walk through
+//	    them and opaque blocks line of sight
+//
+//	exit <x> <y> <level> [<destX> <destY> <angleDegrees>]
+//	    entering cell (x,y) moves the player to the given level, at the destination position
+//	    if given otherwise at the spawn of that level
+//
+//	door <x> <y> [key=<name>] [close=<seconds>]
+//	    makes the ground level wall cell (x,y) a sliding door using that cell's texture,
+//	    locked doors need the named key to open, and open doors close again after the
+//	    given number of seconds (default 3, 0 stays open)
+//
+//	sprite <archetype> <x> <y> [<interaction>] [angle=<degrees>] [scale=<n>] [color=<mapColor>] [health=<n>] [armor=<n>] [key=<name>] [patrol=<x>,<y>;...]
+//	    places a sprite of the named archetype from the sprite archetype file, optionally
+//	    heading in the given direction and with its own scale or hex minimap color, the
+//	    optional interaction names a registered interaction such as "pickup" or "talk",
+//	    sprites given health can be damaged and killed, sprites given a key are picked up
+//	    as that key, and sprites with AI patrol the cells of the given waypoints in turn
+//
+// Grid rows are either whitespace separated numbers or a run of single digits such as
+// "1000001". Texture number 0 means empty, every other number used in a grid must be given
+// a texture name. Every grid in a file must have the same dimensions.
+
+const mapsDir = "resources/maps"
+
+// spritePlacement describes a sprite to be created when the level's sprites are loaded
+type spritePlacement struct {
+	archetype   string
+	x, y        float64
+	angle       float64
+	scale       float64     // 0 uses the archetype's scale
+	mapColor    *color.RGBA // nil uses the archetype's color
+	interaction string
+	health      float64
+	armor       float64
+	key         string
+	waypoints   []geom.Vector2
+}
+
+// loadMapFile reads and parses a map file from the embedded resources
+func loadMapFile(mapFile string) (*Map, error) {
+	f, err := embedded.Open(filepath.ToSlash(filepath.Join(mapsDir, mapFile)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := parseMap(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", mapFile, err)
+	}
+	return m, nil
+}
+
+// parseMap builds a fully populated Map from map file contents
+func parseMap(r io.Reader) (*Map, error) {
+	m := &Map{
+		spawn: &geom.Vector2{X: 1.5, Y: 1.5},
+		exits: make(map[mapCell]*levelExit),
+		doors: make(map[mapCell]*Door),
+
+		wallTextures: make(map[int]string),
+		flatTextures: make(map[int]string),
+		midTextures:  make(map[int]midTexture),
+	}
+
+	var grid [][]int
+	var gridName string
+	var gridLevel int
+	// optional per level grids by block name and level
+	layers := map[string]map[int][][]int{"floor": {}, "ceiling": {}, "mid": {}}
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if gridName != "" {
+			// inside of a grid block
+			if fields[0] != "end" {
+				row, err := parseMapRow(fields)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+				grid = append(grid, row)
+				continue
+			}
+
+			if len(grid) == 0 {
+				return nil, fmt.Errorf("line %d: empty %s block", lineNum, gridName)
+			}
+			switch gridName {
+			case "walls":
+				m.wallMaps = append(m.wallMaps, grid)
+			default:
+				layers[gridName][gridLevel] = grid
+			}
+			grid, gridName = nil, ""
+			continue
+		}
+
+		switch fields[0] {
+		case "walls":
+			if len(fields) != 1 {
+				return nil, fmt.Errorf("line %d: unexpected arguments to %s", lineNum, fields[0])
+			}
+			gridName = fields[0]
+		case "floor", "ceiling", "mid":
+			if len(fields) > 2 {
+				return nil, fmt.Errorf("line %d: unexpected arguments to %s", lineNum, fields[0])
+			}
+			gridLevel = 0
+			if len(fields) == 2 {
+				z, err := strconv.Atoi(fields[1])
+				if err != nil || z < 0 {
+					return nil, fmt.Errorf("line %d: invalid %s level %q", lineNum, fields[0], fields[1])
+				}
+				gridLevel = z
+			}
+			if _, ok := layers[fields[0]][gridLevel]; ok {
+				return nil, fmt.Errorf("line %d: duplicate %s %d block", lineNum, fields[0], gridLevel)
+			}
+			gridName = fields[0]
+		case "spawn":
+			vals, err := parseMapFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: spawn: %w", lineNum, err)
+			}
+			m.spawn = &geom.Vector2{X: vals[0], Y: vals[1]}
+			m.spawnAngle = geom.Radians(vals[2])
+		case "exit":
+			e, cell, err := parseLevelExit(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: exit: %w", lineNum, err)
+			}
+			m.exits[cell] = e
+		case "walltexture", "flattexture":
+			n, name, err := parseTextureName(fields[1:])
+			if err == nil && len(fields) > 3 {
+				err = fmt.Errorf("unexpected arguments")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %w", lineNum, fields[0], err)
+			}
+			names := m.wallTextures
+			if fields[0] == "flattexture" {
+				names = m.flatTextures
+			}
+			if _, ok := names[n]; ok {
+				return nil, fmt.Errorf("line %d: %s %d is already defined", lineNum, fields[0], n)
+			}
+			names[n] = name
+		case "midtexture":
+			n, mt, err := parseMidTexture(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: midtexture: %w", lineNum, err)
+			}
+			if _, ok := m.midTextures[n]; ok {
+				return nil, fmt.Errorf("line %d: midtexture %d is already defined", lineNum, n)
+			}
+			m.midTextures[n] = mt
+		case "door":
+			d, err := parseDoor(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: door: %w", lineNum, err)
+			}
+			m.doors[mapCell{x: d.X, y: d.Y}] = d
+		case "sprite":
+			p, err := parseSpritePlacement(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: sprite: %w", lineNum, err)
+			}
+			m.spritePlacements = append(m.spritePlacements, p)
+		default:
+			return nil, fmt.Errorf("line %d: unknown directive %q", lineNum, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if gridName != "" {
+		return nil, fmt.Errorf("unterminated %s block", gridName)
+	}
+	if len(m.wallMaps) == 0 {
+		return nil, fmt.Errorf("no walls defined")
+	}
+
+	m.zLength = len(m.wallMaps)
+	m.xLength = len(m.wallMaps[0])
+	m.yLength = len(m.wallMaps[0][0])
+
+	// floors, ceilings and mid textures are optional for each level, default to empty
+	for name, levels := range layers {
+		for z := range levels {
+			if z >= m.zLength {
+				return nil, fmt.Errorf("%s %d has no matching walls level", name, z)
+			}
+		}
+	}
+	levelGrids := func(name string) [][][]int {
+		grids := make([][][]int, m.zLength)
+		for z := range grids {
+			grids[z] = layers[name][z]
+			if grids[z] == nil {
+				grids[z] = newGrid(m.xLength, m.yLength)
+			}
+		}
+		return grids
+	}
+	m.floorMaps = levelGrids("floor")
+	m.ceilingMaps = levelGrids("ceiling")
+	m.midMaps = levelGrids("mid")
+
+	grids := map[string][][]int{}
+	for z, wallMap := range m.wallMaps {
+		grids[fmt.Sprintf("walls %d", z)] = wallMap
+		grids[fmt.Sprintf("floor %d", z)] = m.floorMaps[z]
+		grids[fmt.Sprintf("ceiling %d", z)] = m.ceilingMaps[z]
+		grids[fmt.Sprintf("mid %d", z)] = m.midMaps[z]
+	}
+	for name, g := range grids {
+		if len(g) != m.xLength {
+			return nil, fmt.Errorf("%s has %d rows, expected %d", name, len(g), m.xLength)
+		}
+		for x, row := range g {
+			if len(row) != m.yLength {
+				return nil, fmt.Errorf("%s row %d has %d columns, expected %d", name, x, len(row), m.yLength)
+			}
+		}
+	}
+
+	for z, midMap := range m.midMaps {
+		for x, row := range midMap {
+			for y, value := range row {
+				if value > 0 && m.wallMaps[z][x][y] > 0 {
+					return nil, fmt.Errorf("mid %d (%d,%d) is inside of a wall", z, x, y)
+				}
+			}
+		}
+	}
+
+	// every texture number used must have a texture name
+	midNumbers := make(map[int]string, len(m.midTextures))
+	for n, mt := range m.midTextures {
+		midNumbers[n] = mt.name
+	}
+	textureNames := map[string]map[int]string{"walls": m.wallTextures, "floor": m.flatTextures, "ceiling": m.flatTextures, "mid": midNumbers}
+	gridLayers := map[string][][][]int{"walls": m.wallMaps, "floor": m.floorMaps, "ceiling": m.ceilingMaps, "mid": m.midMaps}
+	for name, levels := range gridLayers {
+		for z, g := range levels {
+			for x, row := range g {
+				for y, value := range row {
+					if _, ok := textureNames[name][value]; value > 0 && !ok {
+						return nil, fmt.Errorf("%s %d (%d,%d) uses texture %d which has no name", name, z, x, y, value)
+					}
+				}
+			}
+		}
+	}
+
+	// also checks the default spawn when the map does not give one
+	if !m.isOpenCell(m.spawn.X, m.spawn.Y) {
+		return nil, fmt.Errorf("spawn (%v,%v) is not an open cell", m.spawn.X, m.spawn.Y)
+	}
+
+	for cell := range m.exits {
+		if !m.inBounds(float64(cell.x), float64(cell.y)) {
+			return nil, fmt.Errorf("exit (%d,%d) is outside of the map", cell.x, cell.y)
+		}
+		if m.isSolid(0, cell.x, cell.y) {
+			return nil, fmt.Errorf("exit (%d,%d) is inside of a wall", cell.x, cell.y)
+		}
+	}
+
+	for _, p := range m.spritePlacements {
+		if !m.isOpenCell(p.x, p.y) {
+			return nil, fmt.Errorf("%s (%v,%v) is not in an open cell", p.archetype, p.x, p.y)
+		}
+		for _, wp := range p.waypoints {
+			if !m.isOpenCell(wp.X, wp.Y) {
+				return nil, fmt.Errorf("%s waypoint (%v,%v) is not an open cell", p.archetype, wp.X, wp.Y)
+			}
+		}
+	}
+
+	for cell, d := range m.doors {
+		if !m.inBounds(float64(cell.x), float64(cell.y)) {
+			return nil, fmt.Errorf("door (%d,%d) is outside of the map", cell.x, cell.y)
+		}
+		d.Texture = m.wallMaps[0][cell.x][cell.y]
+		if d.Texture <= 0 {
+			return nil, fmt.Errorf("door (%d,%d) is not a wall cell", cell.x, cell.y)
+		}
+	}
+
+	m.boundaryLines = m.GetBoundaryLines(.2)
+	m.collisionMaps = make([][]geom.Line, m.zLength)
+	for z := range m.wallMaps {
+		m.collisionMaps[z] = m.GetCollisionLines(z, .2)
+	}
+	m.grid = newSpatialGrid(m.boundaryLines, m.collisionMaps)
+	return m, nil
+}
+
+func parseMapRow(fields []string) ([]int, error) {
+	if len(fields) == 1 && len(fields[0]) > 1 {
+		// compact row of single digit cells
+		row := make([]int, len(fields[0]))
+		for i, c := range fields[0] {
+			if c < '0' || c > '9' {
+				return nil, fmt.Errorf("invalid cell %q", c)
+			}
+			row[i] = int(c - '0')
+		}
+		return row, nil
+	}
+
+	row := make([]int, len(fields))
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid cell %q", f)
+		}
+		row[i] = v
+	}
+	return row, nil
+}
+
+func parseMapFloats(fields []string, count int) ([]float64, error) {
+	if len(fields) != count {
+		return nil, fmt.Errorf("expected %d values, got %d", count, len(fields))
+	}
+	vals := make([]float64, count)
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", f)
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func parseLevelExit(fields []string) (*levelExit, mapCell, error) {
+	cell := mapCell{}
+	if len(fields) != 3 && len(fields) != 6 {
+		return nil, cell, fmt.Errorf("expected 3 or 6 values, got %d", len(fields))
+	}
+
+	ints := make([]int, 3)
+	for i, f := range fields[:3] {
+		v, err := strconv.Atoi(f)
+		if err != nil || v < 0 {
+			return nil, cell, fmt.Errorf("invalid value %q", f)
+		}
+		ints[i] = v
+	}
+	cell.x, cell.y = ints[0], ints[1]
+
+	e := &levelExit{level: ints[2]}
+	if len(fields) == 6 {
+		vals, err := parseMapFloats(fields[3:], 3)
+		if err != nil {
+			return nil, cell, err
+		}
+		e.dest = &geom.Vector2{X: vals[0], Y: vals[1]}
+		e.destAngle = geom.Radians(vals[2])
+	}
+	return e, cell, nil
+}
+
+func parseSpritePlacement(fields []string) (spritePlacement, error) {
+	p := spritePlacement{}
+	if len(fields) < 3 {
+		return p, fmt.Errorf("expected at least 3 values, got %d", len(fields))
+	}
+
+	vals, err := parseMapFloats(fields[1:3], 2)
+	if err != nil {
+		return p, err
+	}
+	p.archetype = fields[0]
+	p.x, p.y = vals[0], vals[1]
+
+	for _, f := range fields[3:] {
+		key, value, isOption := strings.Cut(f, "=")
+		if !isOption {
+			if _, ok := interactions[f]; !ok {
+				return p, fmt.Errorf("unknown interaction %q", f)
+			}
+			p.interaction = f
+			continue
+		}
+		if key == "key" {
+			if value == "" {
+				return p, fmt.Errorf("empty key name")
+			}
+			p.key = value
+			continue
+		}
+		if key == "patrol" {
+			waypoints, err := parseWaypoints(value)
+			if err != nil {
+				return p, err
+			}
+			p.waypoints = waypoints
+			continue
+		}
+		if key == "color" {
+			clr, err := parseHexColor(value)
+			if err != nil {
+				return p, err
+			}
+			p.mapColor = &clr
+			continue
+		}
+
+		v, err := strconv.ParseFloat(value, 64)
+		if key == "angle" && err == nil {
+			p.angle = geom.Radians(v)
+			continue
+		}
+		if err != nil || v < 0 {
+			return p, fmt.Errorf("invalid %s %q", key, value)
+		}
+		switch key {
+		case "scale":
+			if v == 0 {
+				return p, fmt.Errorf("invalid %s %q", key, value)
+			}
+			p.scale = v
+		case "health":
+			p.health = v
+		case "armor":
+			p.armor = v
+		default:
+			return p, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	if p.key != "" && p.interaction == "" {
+		p.interaction = "pickup"
+	}
+	return p, nil
+}
+
+// parseWaypoints parses a list of positions in the form x,y;x,y;...
+func parseWaypoints(s string) ([]geom.Vector2, error) {
+	waypoints := []geom.Vector2{}
+	for _, wp := range strings.Split(s, ";") {
+		xs, ys, ok := strings.Cut(wp, ",")
+		x, errX := strconv.ParseFloat(xs, 64)
+		y, errY := strconv.ParseFloat(ys, 64)
+		if !ok || errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid waypoint %q", wp)
+		}
+		waypoints = append(waypoints, geom.Vector2{X: x, Y: y})
+	}
+	return waypoints, nil
+}
+
+// parseTextureName parses a texture number and the name it refers to
+func parseTextureName(fields []string) (int, string, error) {
+	if len(fields) < 2 {
+		return 0, "", fmt.Errorf("expected a texture number and name")
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n <= 0 {
+		return 0, "", fmt.Errorf("invalid texture number %q", fields[0])
+	}
+	return n, fields[1], nil
+}
+
+func parseMidTexture(fields []string) (int, midTexture, error) {
+	mt := midTexture{}
+	n, name, err := parseTextureName(fields)
+	if err != nil {
+		return 0, mt, err
+	}
+	mt.name = name
+	for _, f := range fields[2:] {
+		switch f {
+		case "passable":
+			mt.passable = true
+		case "opaque":
+			mt.opaque = true
+		default:
+			return 0, mt, fmt.Errorf("unknown flag %q", f)
+		}
+	}
+	return n, mt, nil
+}
+
+func parseDoor(fields []string) (*Door, error) {
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("expected at least 2 values, got %d", len(fields))
+	}
+	x, errX := strconv.Atoi(fields[0])
+	y, errY := strconv.Atoi(fields[1])
+	if errX != nil || errY != nil {
+		return nil, fmt.Errorf("invalid cell %q %q", fields[0], fields[1])
+	}
+
+	d := &Door{X: x, Y: y, CloseDelay: doorCloseDelay}
+	for _, f := range fields[2:] {
+		key, value, isOption := strings.Cut(f, "=")
+		if !isOption {
+			return nil, fmt.Errorf("unexpected value %q", f)
+		}
+		switch key {
+		case "key":
+			if value == "" {
+				return nil, fmt.Errorf("empty key name")
+			}
+			d.Key = value
+		case "close":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				return nil, fmt.Errorf("invalid close %q", value)
+			}
+			d.CloseDelay = seconds
+		default:
+			return nil, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	return d, nil
+}
+
+// parseHexColor parses colors in the form RRGGBB or RRGGBBAA
+func parseHexColor(s string) (color.RGBA, error) {
+	if len(s) == 6 {
+		s += "ff"
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || len(s) != 8 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+func newGrid(xLength, yLength int) [][]int {
+	g := make([][]int, xLength)
+	for x := range g {
+		g[x] = make([]int, yLength)
+	}
+	return g
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+const (
+	minimapCellSize = 4
+	minimapMargin   = 10
+
+	// how far and how finely the player's view is traced to reveal the automap
+	revealDistance = 16.0
+	revealRays     = 48
+)
+
+var (
+	mapWallColor   = color.RGBA{128, 128, 128, 220}
+	mapFloorColor  = color.RGBA{32, 32, 32, 160}
+	mapBackColor   = color.RGBA{0, 0, 0, 200}
+	mapExitColor   = color.RGBA{32, 96, 160, 220}
+	mapDoorColor   = color.RGBA{160, 112, 48, 220}
+	mapMidColor    = color.RGBA{96, 96, 96, 180}
+	mapViewColor   = color.RGBA{255, 255, 0, 160}
+	mapUnseenColor = color.RGBA{0, 0, 0, 0}
+)
+
+// reveal marks a cell of the map as seen by the player for the automap
+func (m *Map) reveal(x, y int) {
+	if x < 0 || y < 0 || x >= m.xLength || y >= m.yLength {
+		return
+	}
+	if m.seen == nil {
+		m.seen = make([][]bool, m.xLength)
+		for i := range m.seen {
+			m.seen[i] = make([]bool, m.yLength)
+		}
+	}
+	m.seen[x][y] = true
+}
+
+func (m *Map) isSeen(x, y int) bool {
+	return m.seen != nil && x >= 0 && y >= 0 && x < m.xLength && y < m.yLength && m.seen[x][y]
+}
+
+// revealView traces rays across the given field of view, marking cells as seen up to and including the first wall
+func (m *Map) revealView(pos *geom.Vector2, angle, fov float64) {
+	m.reveal(int(pos.X), int(pos.Y))
+
+	for i := 0; i < revealRays; i++ {
+		rayAngle := angle - fov/2 + fov*float64(i)/float64(revealRays-1)
+		dirX, dirY := math.Cos(rayAngle), math.Sin(rayAngle)
+
+		// step along the ray in small increments, cheap enough for the few rays needed here
+		for d := 0.0; d < revealDistance; d += 0.25 {
+			x, y := pos.X+dirX*d, pos.Y+dirY*d
+			if !m.inBounds(x, y) {
+				break
+			}
+			ix, iy := int(x), int(y)
+			m.reveal(ix, iy)
+			if m.blocksSight(0, ix, iy) {
+				break
+			}
+		}
+	}
+}
+
+// updateAutomap reveals what the player can currently see
+func (g *Game) updateAutomap() {
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	currentMap.revealView(g.player.Position, g.player.Angle, geom.Radians(g.fovDegrees))
+}
+
+// drawMinimap draws the whole level in the top right corner of the screen
+func (g *Game) drawMinimap(screen *ebiten.Image) {
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	mapWidth := float64(currentMap.xLength * minimapCellSize)
+	originX := float64(g.screenWidth) - mapWidth - minimapMargin
+	originY := float64(minimapMargin)
+
+	g.drawMapView(screen, originX, originY, minimapCellSize, false)
+}
+
+// drawAutomap draws the level scaled to fill the screen, only showing cells the player has seen
+func (g *Game) drawAutomap(screen *ebiten.Image) {
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	vector.DrawFilledRect(screen, 0, 0, float32(g.screenWidth), float32(g.screenHeight), mapBackColor, false)
+
+	cellSize := math.Floor(math.Min(
+		float64(g.screenWidth-2*minimapMargin)/float64(currentMap.xLength),
+		float64(g.screenHeight-2*minimapMargin)/float64(currentMap.yLength),
+	))
+	originX := (float64(g.screenWidth) - cellSize*float64(currentMap.xLength)) / 2
+	originY := (float64(g.screenHeight) - cellSize*float64(currentMap.yLength)) / 2
+
+	g.drawMapView(screen, originX, originY, cellSize, true)
+}
+
+// drawMapView draws walls, sprites and the player's view cone. Map Y is flipped so that
+// the map is drawn with the same handedness as the rendered view.
+func (g *Game) drawMapView(screen *ebiten.Image, originX, originY, cellSize float64, fog bool) {
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	toScreen := func(x, y float64) (float32, float32) {
+		return float32(originX + x*cellSize), float32(originY + (float64(currentMap.yLength)-y)*cellSize)
+	}
+
+	cs := float32(cellSize)
+	for x, row := range currentMap.wallMaps[0] {
+		for y, value := range row {
+			clr := mapFloorColor
+			switch {
+			case fog && !currentMap.isSeen(x, y):
+				clr = mapUnseenColor
+			case currentMap.doorAt(x, y) != nil:
+				clr = mapDoorColor
+			case value > 0:
+				clr = mapWallColor
+			case currentMap.midMaps[0][x][y] > 0:
+				clr = mapMidColor
+			case currentMap.exits[mapCell{x: x, y: y}] != nil:
+				clr = mapExitColor
+			}
+			if clr.A == 0 {
+				continue
+			}
+			sx, sy := toScreen(float64(x), float64(y+1))
+			vector.DrawFilledRect(screen, sx, sy, cs, cs, clr, false)
+		}
+	}
+
+	for sprite := range currentMap.sprites {
+		if fog && !currentMap.isSeen(int(sprite.Position.X), int(sprite.Position.Y)) {
+			continue
+		}
+		if !currentMap.inBounds(sprite.Position.X, sprite.Position.Y) {
+			continue
+		}
+		clr := sprite.MapColor
+		clr.A = 255
+		sx, sy := toScreen(sprite.Position.X, sprite.Position.Y)
+		radius := math.Max(sprite.CollisionRadius*cellSize, cellSize/4)
+		vector.DrawFilledCircle(screen, sx, sy, float32(radius), clr, true)
+	}
+
+	// player view cone
+	px, py := toScreen(g.player.Position.X, g.player.Position.Y)
+	halfFov := geom.Radians(g.fovDegrees) / 2
+	coneLength := 3.0
+	for _, a := range []float64{g.player.Angle - halfFov, g.player.Angle + halfFov} {
+		line := geom.LineFromAngle(g.player.Position.X, g.player.Position.Y, a, coneLength)
+		ex, ey := toScreen(line.X2, line.Y2)
+		vector.StrokeLine(screen, px, py, ex, ey, 1, mapViewColor, true)
+	}
+
+	playerRadius := math.Max(g.player.CollisionRadius*cellSize, 2)
+	vector.DrawFilledCircle(screen, px, py, float32(playerRadius), g.player.MapColor, true)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"container/heap"
+	"math"
+)
+
+// neighbours of a cell in the order they are searched, diagonals last
+var pathSteps = []mapCell{
+	{x: 1, y: 0}, {x: -1, y: 0}, {x: 0, y: 1}, {x: 0, y: -1},
+	{x: 1, y: 1}, {x: 1, y: -1}, {x: -1, y: 1}, {x: -1, y: -1},
+}
+
+// isWalkable returns true if an entity of the given collision radius can stand in the middle of
+// the ground level cell. Entities up to half a cell across fit in any open cell, wider ones also
+// need the cells around it to be open.
+func (m *Map) isWalkable(x, y int, radius float64) bool {
+	k := int(math.Ceil(radius - 0.5))
+	for i := x - k; i <= x+k; i++ {
+		for j := y - k; j <= y+k; j++ {
+			if i < 0 || j < 0 || i >= m.xLength || j >= m.yLength || m.isSolid(0, i, j) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// pathNeighbours returns the walkable cells next to a cell. Diagonal steps are only taken when
+// both cells beside them are walkable, so that paths never cut wall corners.
+func (m *Map) pathNeighbours(c mapCell, radius float64) []mapCell {
+	cells := make([]mapCell, 0, len(pathSteps))
+	for _, step := range pathSteps {
+		n := mapCell{x: c.x + step.x, y: c.y + step.y}
+		if !m.isWalkable(n.x, n.y, radius) {
+			continue
+		}
+		if step.x != 0 && step.y != 0 &&
+			(!m.isWalkable(c.x+step.x, c.y, radius) || !m.isWalkable(c.x, c.y+step.y, radius)) {
+			continue
+		}
+		cells = append(cells, n)
+	}
+	return cells
+}
+
+// findPath uses A* to find the shortest path over the ground level between two cells for an
+// entity of the given collision radius. The path excludes the starting cell and ends with the
+// goal, nil is returned if the goal cannot be reached.
+func (m *Map) findPath(from, to mapCell, radius float64) []mapCell {
+	if !m.isWalkable(to.x, to.y, radius) {
+		return nil
+	}
+	if from == to {
+		return []mapCell{}
+	}
+
+	cameFrom := map[mapCell]mapCell{}
+	cost := map[mapCell]float64{from: 0}
+	open := &pathQueue{}
+	heap.Push(open, &pathNode{cell: from, priority: octileDistance(from, to)})
+
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*pathNode).cell
+		if current == to {
+			return tracePath(cameFrom, from, to)
+		}
+
+		for _, n := range m.pathNeighbours(current, radius) {
+			stepCost := 1.0
+			if n.x != current.x && n.y != current.y {
+				stepCost = math.Sqrt2
+			}
+			newCost := cost[current] + stepCost
+			if c, ok := cost[n]; ok && c <= newCost {
+				continue
+			}
+			cost[n] = newCost
+			cameFrom[n] = current
+			heap.Push(open, &pathNode{cell: n, priority: newCost + octileDistance(n, to)})
+		}
+	}
+	return nil
+}
+
+// findFleePath searches up to maxSteps cells away from the start for the reachable cell furthest
+// from the threat, returning the path to it or nil if there is nowhere further away to go
+func (m *Map) findFleePath(from mapCell, threatX, threatY float64, maxSteps int, radius float64) []mapCell {
+	threatDistance := func(c mapCell) float64 {
+		return math.Hypot(float64(c.x)+0.5-threatX, float64(c.y)+0.5-threatY)
+	}
+
+	cameFrom := map[mapCell]mapCell{}
+	steps := map[mapCell]int{from: 0}
+	queue := []mapCell{from}
+	best, bestDistance := from, threatDistance(from)
+
+	// a breadth first search is enough here, only the end point needs to be the best
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if steps[current] >= maxSteps {
+			continue
+		}
+		for _, n := range m.pathNeighbours(current, radius) {
+			if _, ok := steps[n]; ok {
+				continue
+			}
+			steps[n] = steps[current] + 1
+			cameFrom[n] = current
+			queue = append(queue, n)
+			if d := threatDistance(n); d > bestDistance {
+				best, bestDistance = n, d
+			}
+		}
+	}
+
+	if best == from {
+		return nil
+	}
+	return tracePath(cameFrom, from, best)
+}
+
+func tracePath(cameFrom map[mapCell]mapCell, from, to mapCell) []mapCell {
+	path := []mapCell{}
+	for c := to; c != from; c = cameFrom[c] {
+		path = append(path, c)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// octileDistance is the length of the shortest path between two cells on an open grid
+// with diagonal steps
+func octileDistance(a, b mapCell) float64 {
+	dx := math.Abs(float64(a.x - b.x))
+	dy := math.Abs(float64(a.y - b.y))
+	return math.Max(dx, dy) + (math.Sqrt2-1)*math.Min(dx, dy)
+}
+
+type pathNode struct {
+	cell     mapCell
+	priority float64
+}
+
+// pathQueue is a priority queue of cells to search, lowest estimated cost first
+type pathQueue []*pathNode
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q pathQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *pathQueue) Push(x any) {
+	*q = append(*q, x.(*pathNode))
+}
+
+func (q *pathQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
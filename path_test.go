@@ -0,0 +1,152 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func pathMap(t *testing.T, walls string) *Map {
+	t.Helper()
+	m, err := parseMap(strings.NewReader("walltexture 1 stone\nwalls\n" + walls + "end\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// twoRooms is a pair of 3x7 rooms joined by a one cell gap at (4,4)
+const twoRooms = `111111111
+100000001
+100000001
+100000001
+111101111
+100000001
+100000001
+100000001
+111111111
+`
+
+// checkPath fails the test unless each step of the path is to a walkable neighbouring cell
+// without cutting a wall corner
+func checkPath(t *testing.T, m *Map, from mapCell, path []mapCell, radius float64) {
+	t.Helper()
+	prev := from
+	for _, c := range path {
+		dx, dy := c.x-prev.x, c.y-prev.y
+		if dx < -1 || dx > 1 || dy < -1 || dy > 1 || (dx == 0 && dy == 0) {
+			t.Fatalf("step from %v to %v in %v", prev, c, path)
+		}
+		if !m.isWalkable(c.x, c.y, radius) {
+			t.Fatalf("step onto unwalkable %v in %v", c, path)
+		}
+		if dx != 0 && dy != 0 && (!m.isWalkable(prev.x+dx, prev.y, radius) || !m.isWalkable(prev.x, prev.y+dy, radius)) {
+			t.Fatalf("step from %v to %v cuts a corner in %v", prev, c, path)
+		}
+		prev = c
+	}
+}
+
+func TestFindPath(t *testing.T) {
+	rooms := pathMap(t, twoRooms)
+	// the cell at (3,3) is walled in
+	ring := pathMap(t, `1111111
+1000001
+1011101
+1010101
+1011101
+1000001
+1111111
+`)
+
+	tests := []struct {
+		name     string
+		m        *Map
+		from, to mapCell
+		radius   float64
+		want     []mapCell
+	}{
+		{name: "same cell", m: rooms, from: mapCell{x: 2, y: 2}, to: mapCell{x: 2, y: 2}, radius: 0.2, want: []mapCell{}},
+		{name: "diagonal across open floor", m: rooms, from: mapCell{x: 1, y: 1}, to: mapCell{x: 3, y: 3}, radius: 0.2,
+			want: []mapCell{{x: 2, y: 2}, {x: 3, y: 3}}},
+		{name: "through the gap", m: rooms, from: mapCell{x: 2, y: 4}, to: mapCell{x: 6, y: 4}, radius: 0.2,
+			want: []mapCell{{x: 3, y: 4}, {x: 4, y: 4}, {x: 5, y: 4}, {x: 6, y: 4}}},
+		// stepping diagonally into and out of the gap would clip the walls beside it
+		{name: "no corner cutting", m: rooms, from: mapCell{x: 3, y: 3}, to: mapCell{x: 5, y: 5}, radius: 0.2,
+			want: []mapCell{{x: 3, y: 4}, {x: 4, y: 4}, {x: 5, y: 4}, {x: 5, y: 5}}},
+		{name: "too wide for the gap", m: rooms, from: mapCell{x: 2, y: 4}, to: mapCell{x: 6, y: 4}, radius: 0.6},
+		{name: "goal is a wall", m: rooms, from: mapCell{x: 2, y: 2}, to: mapCell{x: 4, y: 3}, radius: 0.2},
+		{name: "goal too close to a wall", m: rooms, from: mapCell{x: 2, y: 4}, to: mapCell{x: 1, y: 1}, radius: 0.6},
+		{name: "goal outside the map", m: rooms, from: mapCell{x: 2, y: 2}, to: mapCell{x: 9, y: 2}, radius: 0.2},
+		{name: "goal walled in", m: ring, from: mapCell{x: 1, y: 1}, to: mapCell{x: 3, y: 3}, radius: 0.2},
+		{name: "start walled in", m: ring, from: mapCell{x: 3, y: 3}, to: mapCell{x: 1, y: 1}, radius: 0.2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.m.findPath(tt.from, tt.to, tt.radius)
+			if !reflect.DeepEqual(path, tt.want) {
+				t.Fatalf("got path %v, want %v", path, tt.want)
+			}
+			checkPath(t, tt.m, tt.from, path, tt.radius)
+		})
+	}
+}
+
+func TestFindFleePath(t *testing.T) {
+	rooms := pathMap(t, twoRooms)
+	corridor := pathMap(t, `11111111
+10000001
+11111111
+`)
+
+	tests := []struct {
+		name             string
+		m                *Map
+		from             mapCell
+		threatX, threatY float64
+		maxSteps         int
+		radius           float64
+		want             mapCell
+		wantNil          bool
+	}{
+		{name: "along the corridor", m: corridor, from: mapCell{x: 1, y: 3}, threatX: 1.5, threatY: 1.5, maxSteps: 10, radius: 0.2,
+			want: mapCell{x: 1, y: 6}},
+		{name: "no further than max steps", m: corridor, from: mapCell{x: 1, y: 3}, threatX: 1.5, threatY: 1.5, maxSteps: 2, radius: 0.2,
+			want: mapCell{x: 1, y: 5}},
+		{name: "cornered", m: corridor, from: mapCell{x: 1, y: 6}, threatX: 1.5, threatY: 1.5, maxSteps: 10, radius: 0.2,
+			wantNil: true},
+		{name: "through the gap", m: rooms, from: mapCell{x: 3, y: 4}, threatX: 1.5, threatY: 5.5, maxSteps: 10, radius: 0.2,
+			want: mapCell{x: 7, y: 1}},
+		{name: "too wide for the gap", m: rooms, from: mapCell{x: 2, y: 4}, threatX: 2.5, threatY: 5.5, maxSteps: 10, radius: 0.6,
+			want: mapCell{x: 2, y: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.m.findFleePath(tt.from, tt.threatX, tt.threatY, tt.maxSteps, tt.radius)
+			if tt.wantNil {
+				if path != nil {
+					t.Fatalf("got path %v with nowhere further to go", path)
+				}
+				return
+			}
+			if len(path) == 0 || len(path) > tt.maxSteps {
+				t.Fatalf("got path %v, want 1 to %d steps", path, tt.maxSteps)
+			}
+			checkPath(t, tt.m, tt.from, path, tt.radius)
+
+			end := path[len(path)-1]
+			distance := func(c mapCell) float64 {
+				return math.Hypot(float64(c.x)+0.5-tt.threatX, float64(c.y)+0.5-tt.threatY)
+			}
+			if distance(end) <= distance(tt.from) {
+				t.Errorf("ended %v from the threat at %v, no further than the start's %v", distance(end), end, distance(tt.from))
+			}
+			if end != tt.want {
+				t.Errorf("ended at %v, want %v", end, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+const (
+	// tolerance used when deciding if an entity is standing on a surface
+	groundEpsilon = 0.01
+)
+
+// isGrounded returns true if the entity is resting on the floor or on top of a sprite
+func (g *Game) isGrounded(e *Entity) bool {
+	return e.VelocityZ == 0 && e.PositionZ <= g.groundZ(e)+groundEpsilon
+}
+
+// Jump launches the player upward if standing on something
+func (g *Game) Jump() {
+	if !g.isGrounded(g.player.Entity) {
+		return
+	}
+	g.player.VelocityZ = g.cfg.Gameplay.JumpVelocity
+}
+
+// groundZ returns the height of the highest surface below the entity, which is either
+// the floor, the top of a wall under it, or the top of a sprite whose collision circle it overlaps
+func (g *Game) groundZ(e *Entity) float64 {
+	groundZ := 0.0
+	entityMinZ, _ := zEntityMinMax(e.PositionZ, e)
+
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	for _, cell := range currentMap.cellsUnder(e.Position, e.CollisionRadius) {
+		groundZ = math.Max(groundZ, currentMap.wallTopBelow(cell.x, cell.y, entityMinZ))
+	}
+	for _, sprite := range currentMap.spritesNearCircle(e.Position, e.CollisionRadius) {
+		if sprite.Entity == e || sprite.CollisionRadius <= 0 || sprite.CollisionHeight <= 0 {
+			continue
+		}
+		dist := geom.Distance(e.Position.X, e.Position.Y, sprite.Position.X, sprite.Position.Y)
+		if dist >= e.CollisionRadius+sprite.CollisionRadius {
+			continue
+		}
+
+		_, spriteMaxZ := zEntityMinMax(sprite.PositionZ, sprite.Entity)
+		if spriteMaxZ <= entityMinZ+groundEpsilon && spriteMaxZ > groundZ {
+			groundZ = spriteMaxZ
+		}
+	}
+
+	return groundZ
+}
+
+// ceilingZ returns the height of the lowest wall or sprite bottom above the entity, or -1 if nothing is overhead
+func (g *Game) ceilingZ(e *Entity) float64 {
+	ceilingZ := -1.0
+	_, entityMaxZ := zEntityMinMax(e.PositionZ, e)
+
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	for _, cell := range currentMap.cellsUnder(e.Position, e.CollisionRadius) {
+		wallZ := currentMap.wallBottomAbove(cell.x, cell.y, entityMaxZ)
+		if wallZ >= 0 && (ceilingZ < 0 || wallZ < ceilingZ) {
+			ceilingZ = wallZ
+		}
+	}
+	for _, sprite := range currentMap.spritesNearCircle(e.Position, e.CollisionRadius) {
+		if sprite.Entity == e || sprite.CollisionRadius <= 0 || sprite.CollisionHeight <= 0 {
+			continue
+		}
+		dist := geom.Distance(e.Position.X, e.Position.Y, sprite.Position.X, sprite.Position.Y)
+		if dist >= e.CollisionRadius+sprite.CollisionRadius {
+			continue
+		}
+
+		spriteMinZ, _ := zEntityMinMax(sprite.PositionZ, sprite.Entity)
+		if spriteMinZ >= entityMaxZ-groundEpsilon && (ceilingZ < 0 || spriteMinZ < ceilingZ) {
+			ceilingZ = spriteMinZ
+		}
+	}
+
+	return ceilingZ
+}
+
+// updateEntityZ applies gravity and vertical velocity to the entity, landing it on the
+// floor or the top of a wall or sprite, and returns true if its Z position changed
+func (g *Game) updateEntityZ(e *Entity) bool {
+	// offsets from the anchored Z position to the bottom and top of the entity
+	entityMinZ, entityMaxZ := zEntityMinMax(e.PositionZ, e)
+	bottomOffset, topOffset := e.PositionZ-entityMinZ, entityMaxZ-e.PositionZ
+
+	groundZ := g.groundZ(e)
+	if e.VelocityZ == 0 && entityMinZ <= groundZ {
+		return false
+	}
+
+	e.VelocityZ -= g.cfg.Gameplay.Gravity * g.dt
+	newZ := e.PositionZ + e.VelocityZ*g.dt
+
+	if e.VelocityZ > 0 {
+		// stop rising when hitting the bottom of something overhead
+		if ceilingZ := g.ceilingZ(e); ceilingZ >= 0 && newZ+topOffset > ceilingZ {
+			newZ = ceilingZ - topOffset
+			e.VelocityZ = 0
+		}
+	}
+
+	if newZ-bottomOffset <= groundZ {
+		// landed
+		newZ = groundZ + bottomOffset
+		e.VelocityZ = 0
+	}
+
+	if newZ == e.PositionZ {
+		return false
+	}
+	e.PositionZ = newZ
+	return true
+}
+
+// updatePlayerZ handles jumping and falling for the player
+func (g *Game) updatePlayerZ() {
+	if g.updateEntityZ(g.player.Entity) {
+		g.player.Moved = true
+	}
+}
@@ -6,10 +6,20 @@ import (
 	"github.com/harbdog/raycaster-go/geom"
 )
 
+const (
+	playerStandCameraZ     = 0.5
+	playerStandHeight      = 0.5
+	playerCrouchCameraZ    = 0.3
+	playerCrouchHeight     = 0.3
+	playerCrouchMoveFactor = 0.5
+)
+
 type Player struct {
 	*Entity
-	CameraZ float64
-	Moved   bool
+	CameraZ   float64
+	Moved     bool
+	Crouching bool
+	Keys      map[string]bool
 }
 
 func NewPlayer(x, y, angle, pitch float64) *Player {
@@ -20,11 +30,22 @@ func NewPlayer(x, y, angle, pitch float64) *Player {
 			Angle:     angle,
 			Pitch:     pitch,
 			Velocity:  0,
+			Health:    playerMaxHealth,
+			MaxHealth: playerMaxHealth,
 			MapColor:  color.RGBA{255, 0, 0, 255},
 		},
-		CameraZ: 0.5,
+		CameraZ: playerStandCameraZ,
 		Moved:   false,
+		Keys:    make(map[string]bool),
 	}
 
 	return p
 }
+
+// newSpawnedPlayer creates a standing player at the map's spawn point
+func newSpawnedPlayer(m *Map) *Player {
+	p := NewPlayer(m.spawn.X, m.spawn.Y, m.spawnAngle, 0)
+	p.CollisionRadius = 0.2
+	p.CollisionHeight = playerStandHeight
+	return p
+}
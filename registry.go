@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// The texture manifest lists every texture the game loads, one per line, as
+//
+//	<kind> <name> <file>
+//
+// where kind is one of wall, flat (floor and ceiling), mid or sprite, name is how maps
+// refer to the texture and file is relative to the resources directory. Blank lines are
+// ignored and anything following a '#' is treated as a comment. Names must be unique
+// within each kind, but the same file may be listed under more than one kind.
+const textureManifest = "resources/textures.txt"
+
+var textureKinds = []string{"wall", "flat", "mid", "sprite"}
+
+// textureEntry is a texture loaded from the manifest
+type textureEntry struct {
+	id    int
+	kind  string
+	name  string
+	file  string
+	image *ebiten.Image // wall and sprite textures
+	rgba  *image.RGBA   // flat and mid textures
+}
+
+// textureRegistry holds every texture from the manifest, by ID and by kind and name
+type textureRegistry struct {
+	entries []*textureEntry
+	byName  map[string]map[string]*textureEntry
+}
+
+// loadTextureRegistry reads the manifest and loads every texture in it, reporting all
+// missing, invalid and duplicate textures together
+func loadTextureRegistry(manifest string) (*textureRegistry, error) {
+	f, err := embedded.Open(filepath.ToSlash(manifest))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := &textureRegistry{byName: make(map[string]map[string]*textureEntry)}
+	for _, kind := range textureKinds {
+		r.byName[kind] = make(map[string]*textureEntry)
+	}
+
+	var errs []error
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err := r.add(fields); err != nil {
+			errs = append(errs, fmt.Errorf("%s line %d: %w", manifest, lineNum, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// add loads a texture from a manifest line and assigns it the next ID
+func (r *textureRegistry) add(fields []string) error {
+	if len(fields) != 3 {
+		return fmt.Errorf("expected 3 values, got %d", len(fields))
+	}
+	kind, name, file := fields[0], fields[1], fields[2]
+
+	names, ok := r.byName[kind]
+	if !ok {
+		return fmt.Errorf("unknown texture kind %q", kind)
+	}
+	if existing, ok := names[name]; ok {
+		return fmt.Errorf("duplicate %s texture %q, already loaded from %s", kind, name, existing.file)
+	}
+
+	e := &textureEntry{id: len(r.entries), kind: kind, name: name, file: file}
+	img, err := loadImageFile("resources/" + file)
+	if err != nil {
+		return fmt.Errorf("%s texture %q: %w", kind, name, err)
+	}
+	if kind == "sprite" {
+		e.image = ebiten.NewImageFromImage(img)
+	} else {
+		rgba, err := rgbaFromImage(img)
+		if err != nil {
+			return fmt.Errorf("%s texture %q: %s: %w", kind, name, file, err)
+		}
+		if kind == "wall" {
+			e.image = ebiten.NewImageFromImage(rgba)
+		} else {
+			e.rgba = rgba
+		}
+	}
+
+	r.entries = append(r.entries, e)
+	names[name] = e
+	return nil
+}
+
+// lookup returns the texture of the given kind and name
+func (r *textureRegistry) lookup(kind, name string) (*textureEntry, error) {
+	e, ok := r.byName[kind][name]
+	if !ok {
+		return nil, fmt.Errorf("unknown %s texture %q", kind, name)
+	}
+	return e, nil
+}
+
+// levelTextures holds the textures of a level indexed by the texture numbers used in its grids
+type levelTextures struct {
+	walls []*ebiten.Image
+	flats []*image.RGBA
+	mids  []*image.RGBA
+}
+
+// resolveLevel looks up every texture name used by the map, reporting all unknown names together
+func (r *textureRegistry) resolveLevel(m *Map) (*levelTextures, error) {
+	var errs []error
+	resolve := func(kind string, names map[int]string) []*textureEntry {
+		entries := []*textureEntry{}
+		for n, name := range names {
+			e, err := r.lookup(kind, name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for len(entries) <= n {
+				entries = append(entries, nil)
+			}
+			entries[n] = e
+		}
+		return entries
+	}
+
+	midNames := make(map[int]string, len(m.midTextures))
+	for n, mt := range m.midTextures {
+		midNames[n] = mt.name
+	}
+
+	lt := &levelTextures{}
+	for _, e := range resolve("wall", m.wallTextures) {
+		lt.walls = append(lt.walls, e.imageOrNil())
+	}
+	for _, e := range resolve("flat", m.flatTextures) {
+		lt.flats = append(lt.flats, e.rgbaOrNil())
+	}
+	for _, e := range resolve("mid", midNames) {
+		lt.mids = append(lt.mids, e.rgbaOrNil())
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return lt, nil
+}
+
+func (e *textureEntry) imageOrNil() *ebiten.Image {
+	if e == nil {
+		return nil
+	}
+	return e.image
+}
+
+func (e *textureEntry) rgbaOrNil() *image.RGBA {
+	if e == nil {
+		return nil
+	}
+	return e.rgba
+}
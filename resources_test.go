@@ -0,0 +1,122 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// halves returns a size x size image of the given type set to one color left of size/2 and
+// another right of it
+func halves(size int, newImage func(image.Rectangle) image.Image, set func(img image.Image, x, y int, left bool)) image.Image {
+	img := newImage(image.Rect(0, 0, size, size))
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			set(img, x, y, x < size/2)
+		}
+	}
+	return img
+}
+
+func TestRGBAFromImage(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	paletted := func(size int) image.Image {
+		return halves(size, func(r image.Rectangle) image.Image {
+			return image.NewPaletted(r, color.Palette{red, blue})
+		}, func(img image.Image, x, y int, left bool) {
+			if left {
+				img.(*image.Paletted).SetColorIndex(x, y, 0)
+			} else {
+				img.(*image.Paletted).SetColorIndex(x, y, 1)
+			}
+		})
+	}
+	nrgba := func(size int) image.Image {
+		return halves(size, func(r image.Rectangle) image.Image {
+			return image.NewNRGBA(r)
+		}, func(img image.Image, x, y int, left bool) {
+			if left {
+				img.(*image.NRGBA).SetNRGBA(x, y, color.NRGBA{255, 0, 0, 255})
+			} else {
+				// half transparent, premultiplied in the result
+				img.(*image.NRGBA).SetNRGBA(x, y, color.NRGBA{0, 0, 255, 128})
+			}
+		})
+	}
+	gray := halves(texWidth, func(r image.Rectangle) image.Image {
+		return image.NewGray(r)
+	}, func(img image.Image, x, y int, left bool) {
+		if left {
+			img.(*image.Gray).SetGray(x, y, color.Gray{40})
+		} else {
+			img.(*image.Gray).SetGray(x, y, color.Gray{200})
+		}
+	})
+	ycbcr := func() image.Image {
+		img := image.NewYCbCr(image.Rect(0, 0, texWidth, texWidth), image.YCbCrSubsampleRatio444)
+		for x := 0; x < texWidth; x++ {
+			for y := 0; y < texWidth; y++ {
+				i := img.YOffset(x, y)
+				img.Y[i], img.Cb[i], img.Cr[i] = 128, 128, 128
+				if x < texWidth/2 {
+					img.Y[i] = 16
+				}
+			}
+		}
+		return img
+	}()
+
+	tests := []struct {
+		name        string
+		img         image.Image
+		wantErr     bool
+		left, right color.RGBA
+	}{
+		{name: "paletted", img: paletted(texWidth), left: red, right: blue},
+		{name: "nrgba", img: nrgba(texWidth), left: red, right: color.RGBA{0, 0, 128, 128}},
+		{name: "gray", img: gray, left: color.RGBA{40, 40, 40, 255}, right: color.RGBA{200, 200, 200, 255}},
+		{name: "ycbcr", img: ycbcr, left: color.RGBA{16, 16, 16, 255}, right: color.RGBA{128, 128, 128, 255}},
+		{name: "smaller square is scaled up", img: paletted(64), left: red, right: blue},
+		{name: "larger square is scaled down", img: nrgba(512), left: red, right: color.RGBA{0, 0, 128, 128}},
+		{name: "sub image", img: paletted(2 * texWidth).(*image.Paletted).SubImage(image.Rect(texWidth/2, 0, 3*texWidth/2, texWidth)), left: red, right: blue},
+		{name: "not square", img: image.NewNRGBA(image.Rect(0, 0, texWidth, texWidth/2)), wantErr: true},
+		{name: "empty", img: image.NewNRGBA(image.Rect(0, 0, 0, 0)), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rgba, err := rgbaFromImage(tt.img)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b := rgba.Bounds(); b != image.Rect(0, 0, texWidth, texWidth) {
+				t.Fatalf("bounds %v, want %dx%d", b, texWidth, texWidth)
+			}
+			// sample away from the edge between the halves where scaling blends them
+			if got := rgba.RGBAAt(texWidth/4, texWidth/2); !closeRGBA(got, tt.left) {
+				t.Errorf("left half %v, want %v", got, tt.left)
+			}
+			if got := rgba.RGBAAt(3*texWidth/4, texWidth/2); !closeRGBA(got, tt.right) {
+				t.Errorf("right half %v, want %v", got, tt.right)
+			}
+		})
+	}
+}
+
+// closeRGBA allows for rounding in color model conversion and scaling
+func closeRGBA(a, b color.RGBA) bool {
+	near := func(x, y uint8) bool {
+		d := int(x) - int(y)
+		return d >= -1 && d <= 1
+	}
+	return near(a.R, b.R) && near(a.G, b.G) && near(a.B, b.B) && near(a.A, b.A)
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+const (
+	saveDir       = "saves"
+	saveSlots     = 5 // numbered slots, as well as the quicksave slot
+	quickSaveSlot = "quick"
+
+	// saveVersion is written to new saves, it must be raised whenever the format changes in a way
+	// older saves cannot simply be read into, with a migration added to saveMigrations
+	saveVersion = 2
+)
+
+// saveMigrations upgrade the decoded JSON of older saves one version at a time, the entry at
+// index i turns a version i+1 save into a version i+2 save. Fields that are added with a
+// sensible zero value, or removed, do not need a migration as unknown fields are ignored.
+var saveMigrations = []func(save map[string]any) error{
+	migrateTicksToSeconds,
+}
+
+// migrateTicksToSeconds converts version 1 saves, which were always made at 60 ticks per second,
+// from door times in ticks and sprite velocities in units per tick to seconds
+func migrateTicksToSeconds(save map[string]any) error {
+	levels, _ := save["levels"].([]any)
+	for _, level := range levels {
+		level, ok := level.(map[string]any)
+		if !ok {
+			return fmt.Errorf("invalid level")
+		}
+		doors, _ := level["doors"].([]any)
+		for _, door := range doors {
+			door, ok := door.(map[string]any)
+			if !ok {
+				return fmt.Errorf("invalid door")
+			}
+			ticks, _ := door["closeCounter"].(float64)
+			delete(door, "closeCounter")
+			door["openTime"] = ticks / 60
+		}
+	}
+
+	sprites, _ := save["sprites"].([]any)
+	for _, sprite := range sprites {
+		sprite, ok := sprite.(map[string]any)
+		if !ok {
+			return fmt.Errorf("invalid sprite")
+		}
+		if v, ok := sprite["velocity"].(float64); ok {
+			sprite["velocity"] = v * 60
+		}
+	}
+	return nil
+}
+
+// saveGame is the state of the world written to a save slot. Doors and the automap are kept for
+// every level, sprites only for the current one as the other levels are restored from their map
+// files when entered. Sprite AI and animations start afresh on loading.
+type saveGame struct {
+	Version int          `json:"version"`
+	Time    time.Time    `json:"time"`
+	Level   int          `json:"level"`
+	Player  savedPlayer  `json:"player"`
+	Weapon  savedWeapon  `json:"weapon"`
+	Levels  []savedLevel `json:"levels"`
+
+	// one for each sprite placed by the current level's map file, in order
+	Sprites []savedSprite `json:"sprites"`
+}
+
+type savedPlayer struct {
+	X         float64  `json:"x"`
+	Y         float64  `json:"y"`
+	Z         float64  `json:"z"`
+	Angle     float64  `json:"angle"`
+	Pitch     float64  `json:"pitch"`
+	Health    float64  `json:"health"`
+	Armor     float64  `json:"armor"`
+	Crouching bool     `json:"crouching"`
+	Keys      []string `json:"keys"`
+}
+
+type savedWeapon struct {
+	Ammo    int `json:"ammo"`
+	Reserve int `json:"reserve"`
+}
+
+type savedLevel struct {
+	Doors []savedDoor `json:"doors"`
+	Seen  []string    `json:"seen"` // a string per column of the map with '1' for seen cells
+}
+
+type savedDoor struct {
+	X        int       `json:"x"`
+	Y        int       `json:"y"`
+	State    doorState `json:"state"`
+	Amount   float64   `json:"amount"`
+	OpenTime float64   `json:"openTime"`
+}
+
+type savedSprite struct {
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Z        float64 `json:"z"`
+	Angle    float64 `json:"angle"`
+	Velocity float64 `json:"velocity"`
+	Health   float64 `json:"health"`
+	Armor    float64 `json:"armor"`
+	Dead     bool    `json:"dead"`
+	Removed  bool    `json:"removed"` // no longer in the level, such as a picked up item
+}
+
+// slotName returns the save slot name of a numbered slot
+func slotName(n int) string {
+	return fmt.Sprintf("slot%d", n)
+}
+
+func savePath(slot string) string {
+	return filepath.Join(saveDir, slot+".json")
+}
+
+// writeSave writes the save to the slot, replacing any save already there
+func writeSave(slot string, s *saveGame) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save %s: %w", slot, err)
+	}
+	if err := os.MkdirAll(saveDir, 0o755); err != nil {
+		return fmt.Errorf("save %s: %w", slot, err)
+	}
+
+	// write to a temporary file first so a failed write never leaves a broken save behind
+	path := savePath(slot)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("save %s: %w", slot, err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("save %s: %w", slot, err)
+	}
+	return nil
+}
+
+// readSave reads the save in the slot, migrating it to the current version if it is older
+func readSave(slot string) (*saveGame, error) {
+	data, err := os.ReadFile(savePath(slot))
+	if err != nil {
+		return nil, fmt.Errorf("save %s: %w", slot, err)
+	}
+	s, err := decodeSave(data)
+	if err != nil {
+		return nil, fmt.Errorf("save %s: %w", slot, err)
+	}
+	return s, nil
+}
+
+func decodeSave(data []byte) (*saveGame, error) {
+	raw := map[string]any{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	version, ok := raw["version"].(float64)
+	if !ok || version < 1 || version != float64(int(version)) {
+		return nil, fmt.Errorf("missing or invalid version")
+	}
+	if int(version) > saveVersion {
+		return nil, fmt.Errorf("version %d is newer than this game supports (%d)", int(version), saveVersion)
+	}
+
+	if int(version) < saveVersion {
+		for v := int(version); v < saveVersion; v++ {
+			if err := saveMigrations[v-1](raw); err != nil {
+				return nil, fmt.Errorf("migrating from version %d: %w", v, err)
+			}
+			raw["version"] = float64(v + 1)
+		}
+		var err error
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &saveGame{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// saveSlotLabel describes the save in the slot for the save and load menus
+func saveSlotLabel(slot, title string) string {
+	if _, err := os.Stat(savePath(slot)); err != nil {
+		return title + ": empty"
+	}
+	s, err := readSave(slot)
+	if err != nil {
+		return title + ": unreadable"
+	}
+	return fmt.Sprintf("%s: level %d, %s", title, s.Level, s.Time.Local().Format("2006-01-02 15:04"))
+}
+
+// saveState captures the current state of the world
+func (g *Game) saveState() *saveGame {
+	p := g.player
+	s := &saveGame{
+		Version: saveVersion,
+		Time:    time.Now(),
+		Level:   g.gameLevels.currentLevel,
+		Player: savedPlayer{
+			X:         p.Position.X,
+			Y:         p.Position.Y,
+			Z:         p.PositionZ,
+			Angle:     p.Angle,
+			Pitch:     p.Pitch,
+			Health:    p.Health,
+			Armor:     p.Armor,
+			Crouching: p.Crouching,
+			Keys:      []string{},
+		},
+		Weapon: savedWeapon{Ammo: g.weapon.Ammo, Reserve: g.weapon.Reserve},
+	}
+	for key, held := range p.Keys {
+		if held {
+			s.Player.Keys = append(s.Player.Keys, key)
+		}
+	}
+	sort.Strings(s.Player.Keys)
+
+	for _, m := range g.gameLevels.levelMaps {
+		level := savedLevel{Doors: []savedDoor{}}
+		for _, d := range m.doors {
+			level.Doors = append(level.Doors, savedDoor{
+				X: d.X, Y: d.Y, State: d.state, Amount: d.amount, OpenTime: d.openTime,
+			})
+		}
+		sort.Slice(level.Doors, func(i, j int) bool {
+			a, b := level.Doors[i], level.Doors[j]
+			return a.X < b.X || a.X == b.X && a.Y < b.Y
+		})
+		if m.seen != nil {
+			for _, column := range m.seen {
+				var sb strings.Builder
+				for _, seen := range column {
+					if seen {
+						sb.WriteByte('1')
+					} else {
+						sb.WriteByte('0')
+					}
+				}
+				level.Seen = append(level.Seen, sb.String())
+			}
+		}
+		s.Levels = append(s.Levels, level)
+	}
+
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	for _, sprite := range currentMap.placedSprites {
+		_, present := currentMap.sprites[sprite]
+		s.Sprites = append(s.Sprites, savedSprite{
+			X:        sprite.Position.X,
+			Y:        sprite.Position.Y,
+			Z:        sprite.PositionZ,
+			Angle:    sprite.Angle,
+			Velocity: sprite.Velocity,
+			Health:   sprite.Health,
+			Armor:    sprite.Armor,
+			Dead:     sprite.IsDead(),
+			// killed sprites without a death animation are replaced by a corpse
+			Removed: !present && !sprite.IsDead(),
+		})
+	}
+	return s
+}
+
+// loadState restores the world from a save. Doors, automap cells and sprites that no longer
+// match the map files, because a level was changed since the game was saved, are skipped.
+// The save's level must exist.
+func (g *Game) loadState(s *saveGame) {
+	levelMaps := g.gameLevels.levelMaps
+	for i, m := range levelMaps {
+		m.resetDoors()
+		m.seen = nil
+		if i >= len(s.Levels) {
+			continue
+		}
+		for _, sd := range s.Levels[i].Doors {
+			d := m.doorAt(sd.X, sd.Y)
+			if d == nil {
+				continue
+			}
+			d.state, d.amount, d.openTime = sd.State, geom.Clamp(sd.Amount, 0, 1), sd.OpenTime
+			m.setDoorSolid(d, d.state != doorOpen)
+		}
+		for x, column := range s.Levels[i].Seen {
+			for y, c := range column {
+				if c == '1' && x < m.xLength && y < m.yLength {
+					m.reveal(x, y)
+				}
+			}
+		}
+	}
+
+	g.playerDead = false
+	g.painTime = 0
+	g.damageEvents = nil
+	g.hudMessage, g.hudMessageTime = "", 0
+
+	g.gameLevels.currentLevel = s.Level
+	g.player = newSpawnedPlayer(levelMaps[s.Level])
+	g.player.PositionZ, g.player.Pitch = s.Player.Z, s.Player.Pitch
+	g.player.Health, g.player.Armor = s.Player.Health, s.Player.Armor
+	for _, key := range s.Player.Keys {
+		g.player.Keys[key] = true
+	}
+
+	g.weapon = NewRevolver()
+	g.weapon.Ammo, g.weapon.Reserve = s.Weapon.Ammo, s.Weapon.Reserve
+
+	pos := &geom.Vector2{X: s.Player.X, Y: s.Player.Y}
+	g.changeLevel(&levelExit{level: s.Level, dest: pos, destAngle: s.Player.Angle})
+	g.Crouch(s.Player.Crouching)
+
+	currentMap := levelMaps[s.Level]
+	for _, d := range currentMap.doors {
+		if d.amount > 0 {
+			g.tex.updateDoorFrame(d)
+		}
+	}
+	for i, sprite := range currentMap.placedSprites {
+		if i >= len(s.Sprites) {
+			break
+		}
+		ss := s.Sprites[i]
+		if ss.Removed {
+			currentMap.removeSprite(sprite)
+			continue
+		}
+		sprite.PositionZ, sprite.Angle, sprite.Velocity = ss.Z, ss.Angle, ss.Velocity
+		sprite.Health, sprite.Armor = ss.Health, ss.Armor
+		currentMap.moveSprite(sprite, &geom.Vector2{X: ss.X, Y: ss.Y})
+		if ss.Dead {
+			// already dead, so lying still rather than dying again
+			g.killSprite(sprite)
+			sprite.EndClip()
+		}
+	}
+
+	g.updatePlayerCamera(true)
+}
+
+// saveToSlot saves the game to the slot, showing on the HUD that it was saved
+func (g *Game) saveToSlot(slot string) error {
+	if err := writeSave(slot, g.saveState()); err != nil {
+		return err
+	}
+	g.showMessage("Game saved")
+	return nil
+}
+
+// loadFromSlot reads the save in the slot and fades to it, returning an error without changing
+// anything if the save cannot be loaded
+func (g *Game) loadFromSlot(slot string) error {
+	s, err := readSave(slot)
+	if err != nil {
+		return err
+	}
+	if s.Level < 0 || s.Level >= len(g.gameLevels.levelMaps) {
+		return fmt.Errorf("save %s: level %d does not exist", slot, s.Level)
+	}
+	g.scenes.fade(func() {
+		g.loadState(s)
+		g.scenes.replace(&gameplayScene{g: g})
+		g.showMessage("Game loaded")
+	})
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecodeSaveVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "current", data: fmt.Sprintf(`{"version": %d, "level": 1}`, saveVersion)},
+		{name: "not json", data: `{"version": `, wantErr: "unexpected end"},
+		{name: "missing", data: `{"level": 1}`, wantErr: "missing or invalid version"},
+		{name: "string", data: `{"version": "2"}`, wantErr: "missing or invalid version"},
+		{name: "zero", data: `{"version": 0}`, wantErr: "missing or invalid version"},
+		{name: "negative", data: `{"version": -1}`, wantErr: "missing or invalid version"},
+		{name: "fraction", data: `{"version": 1.5}`, wantErr: "missing or invalid version"},
+		{name: "newer", data: fmt.Sprintf(`{"version": %d}`, saveVersion+1), wantErr: "newer than this game supports"},
+		{name: "bad migration input", data: `{"version": 1, "levels": [7]}`, wantErr: "migrating from version 1: invalid level"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := decodeSave([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Version != saveVersion || s.Level != 1 {
+				t.Errorf("got version %d level %d, want version %d level 1", s.Version, s.Level, saveVersion)
+			}
+		})
+	}
+}
+
+func TestDecodeSaveMigratesTicksToSeconds(t *testing.T) {
+	// a version 1 save, when door timers and sprite speeds were per tick at 60 ticks per second
+	v1 := `{
+		"version": 1,
+		"level": 0,
+		"levels": [
+			{"doors": [{"x": 9, "y": 10, "state": 1, "amount": 0.5, "closeCounter": 90}], "seen": ["01"]},
+			{"doors": []}
+		],
+		"sprites": [{"x": 2.5, "y": 3.5, "velocity": 0.02}, {"x": 4.5, "y": 5.5}]
+	}`
+
+	s, err := decodeSave([]byte(v1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != saveVersion {
+		t.Errorf("got version %d, want %d", s.Version, saveVersion)
+	}
+	if len(s.Levels) != 2 || len(s.Levels[0].Doors) != 1 {
+		t.Fatalf("got levels %+v, want the door kept", s.Levels)
+	}
+	if d := s.Levels[0].Doors[0]; d.X != 9 || d.Y != 10 || d.Amount != 0.5 || d.OpenTime != 1.5 {
+		t.Errorf("got door %+v, want open for 1.5 seconds", d)
+	}
+	if len(s.Sprites) != 2 {
+		t.Fatalf("got %d sprites, want 2", len(s.Sprites))
+	}
+	if v := s.Sprites[0].Velocity; math.Abs(v-1.2) > 1e-9 {
+		t.Errorf("got sprite velocity %v, want 1.2 cells per second", v)
+	}
+	if v := s.Sprites[1].Velocity; v != 0 {
+		t.Errorf("got velocity %v for a sprite saved without one, want 0", v)
+	}
+}
+
+func TestDecodeSaveRoundTrip(t *testing.T) {
+	want := &saveGame{
+		Version: saveVersion,
+		Level:   1,
+		Player:  savedPlayer{X: 1.5, Y: 2.5, Health: 80, Keys: []string{"pebble"}},
+		Levels:  []savedLevel{{Doors: []savedDoor{{X: 9, Y: 10, State: doorOpen, Amount: 1, OpenTime: 2}}}},
+		Sprites: []savedSprite{{X: 3.5, Y: 4.5, Velocity: 1.2, Dead: true}},
+	}
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeSave(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotData, _ := json.Marshal(got)
+	if string(gotData) != string(data) {
+		t.Errorf("got %s, want %s", gotData, data)
+	}
+}
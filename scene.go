@@ -0,0 +1,189 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	fadeTime = 1.0 / 3 // seconds taken by each half of a fade transition
+
+	menuLineHeight = 24
+	menuTextScale  = 2
+)
+
+var (
+	menuBackColor     = color.RGBA{0, 0, 0, 255}
+	menuOverlayColor  = color.RGBA{0, 0, 0, 160}
+	menuSelectedColor = color.RGBA{96, 64, 16, 200}
+)
+
+// Scene is one screen of the game, such as a menu or gameplay itself
+type Scene interface {
+	Update() error
+	Draw(screen *ebiten.Image)
+	Layout(outsideWidth, outsideHeight int) (int, int)
+
+	// IsOverlay returns true if the scene below should still be drawn underneath this one
+	IsOverlay() bool
+}
+
+// sceneStack holds the active scenes. Only the topmost scene is updated and receives input,
+// scenes are drawn from the topmost one that is not an overlay upwards.
+type sceneStack struct {
+	scenes []Scene
+
+	// fade transitions darken the screen, make their change to the stack, then fade back in
+	fadeRemaining float64 // seconds left until the fade is over
+	fadeChange    func()
+}
+
+func (ss *sceneStack) top() Scene {
+	if len(ss.scenes) == 0 {
+		return nil
+	}
+	return ss.scenes[len(ss.scenes)-1]
+}
+
+// push adds a scene on top of the stack straight away
+func (ss *sceneStack) push(s Scene) {
+	ss.scenes = append(ss.scenes, s)
+}
+
+// pop removes the topmost scene straight away
+func (ss *sceneStack) pop() {
+	if len(ss.scenes) > 0 {
+		ss.scenes = ss.scenes[:len(ss.scenes)-1]
+	}
+}
+
+// replace clears the stack and starts again from the given scene
+func (ss *sceneStack) replace(s Scene) {
+	ss.scenes = []Scene{s}
+}
+
+// fade darkens the screen, applies the change to the stack once it is black, then fades back in
+func (ss *sceneStack) fade(change func()) {
+	if ss.fadeChange != nil {
+		return
+	}
+	ss.fadeChange = change
+	ss.fadeRemaining = 2 * fadeTime
+}
+
+// Update updates the topmost scene, or the fade transition while one is in progress,
+// advancing by dt seconds
+func (ss *sceneStack) Update(dt float64) error {
+	if ss.fadeRemaining > 0 {
+		// scenes are paused while fading
+		ss.fadeRemaining = math.Max(ss.fadeRemaining-dt, 0)
+		if ss.fadeRemaining <= fadeTime && ss.fadeChange != nil {
+			ss.fadeChange()
+			ss.fadeChange = nil
+		}
+		return nil
+	}
+
+	if top := ss.top(); top != nil {
+		return top.Update()
+	}
+	return nil
+}
+
+func (ss *sceneStack) Draw(screen *ebiten.Image) {
+	first := len(ss.scenes) - 1
+	for first > 0 && ss.scenes[first].IsOverlay() {
+		first--
+	}
+	for i := first; i >= 0 && i < len(ss.scenes); i++ {
+		ss.scenes[i].Draw(screen)
+	}
+
+	if ss.fadeRemaining > 0 {
+		// fully black at the midpoint, when the change is made
+		alpha := 1 - math.Abs(ss.fadeRemaining-fadeTime)/fadeTime
+		clr := color.RGBA{0, 0, 0, uint8(255 * alpha)}
+		w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
+		vector.DrawFilledRect(screen, 0, 0, float32(w), float32(h), clr, false)
+	}
+}
+
+func (ss *sceneStack) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return ss.top().Layout(outsideWidth, outsideHeight)
+}
+
+// menuItem is a line of a menu, activated with Enter and changed with Left and Right if adjust is set
+type menuItem struct {
+	label  func() string
+	action func()
+	adjust func(dir int)
+}
+
+// menu is a vertical list of items navigated with the keyboard
+type menu struct {
+	title    string
+	items    []menuItem
+	selected int
+}
+
+func (m *menu) update() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) || inpututil.IsKeyJustPressed(ebiten.KeyW) {
+		m.selected = (m.selected + len(m.items) - 1) % len(m.items)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyDown) || inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		m.selected = (m.selected + 1) % len(m.items)
+	}
+
+	item := m.items[m.selected]
+	if item.adjust != nil {
+		if inpututil.IsKeyJustPressed(ebiten.KeyLeft) || inpututil.IsKeyJustPressed(ebiten.KeyA) {
+			item.adjust(-1)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyRight) || inpututil.IsKeyJustPressed(ebiten.KeyD) {
+			item.adjust(1)
+		}
+	}
+	if item.action != nil && (inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeySpace)) {
+		item.action()
+	}
+}
+
+func (m *menu) draw(screen *ebiten.Image) {
+	w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
+	top := h/2 - (len(m.items)+2)*menuLineHeight/2
+
+	drawTextCentered(screen, m.title, w/2, top, menuTextScale)
+	for i, item := range m.items {
+		y := top + (i+2)*menuLineHeight
+		if i == m.selected {
+			vector.DrawFilledRect(screen, float32(w/4), float32(y-4), float32(w/2), menuLineHeight, menuSelectedColor, false)
+		}
+		drawTextCentered(screen, item.label(), w/2, y, menuTextScale)
+	}
+}
+
+// scratch image that text is printed to before being scaled onto the screen
+var textImage = ebiten.NewImage(640, 16)
+
+// drawTextCentered draws debug font text scaled up and centered horizontally on x
+func drawTextCentered(screen *ebiten.Image, msg string, x, y int, scale float64) {
+	if msg == "" {
+		return
+	}
+
+	// the debug font is 6x16 pixels per character
+	textW := len(msg) * 6
+	textImage.Clear()
+	ebitenutil.DebugPrint(textImage, msg)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(float64(x)-float64(textW)*scale/2, float64(y))
+	screen.DrawImage(textImage.SubImage(image.Rect(0, 0, textW, 16)).(*ebiten.Image), op)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const gameTitle = "Game file"
+
+var deathOverlayColor = color.RGBA{96, 0, 0, 128}
+
+// menuScene is the base of the menu scenes, showing a menu over either a blank screen or, for
+// overlays, a darkened view of the scene below
+type menuScene struct {
+	g       *Game
+	menu    *menu
+	overlay bool
+	back    func() // called when Escape is pressed, if set
+	message string // shown below the menu, such as why a save could not be loaded
+}
+
+func (s *menuScene) Update() error {
+	if s.g.mouseMode != MouseModeCursor {
+		s.g.setMouseMode(MouseModeCursor)
+	}
+	if s.back != nil && inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		s.back()
+		return nil
+	}
+	s.menu.update()
+	if s.g.quit {
+		return ebiten.Termination
+	}
+	return nil
+}
+
+func (s *menuScene) Draw(screen *ebiten.Image) {
+	clr := menuBackColor
+	if s.overlay {
+		clr = menuOverlayColor
+	}
+	vector.DrawFilledRect(screen, 0, 0, float32(s.g.screenWidth), float32(s.g.screenHeight), clr, false)
+	s.menu.draw(screen)
+	drawTextCentered(screen, s.message, s.g.screenWidth/2, s.g.screenHeight-2*menuLineHeight, 1)
+}
+
+func (s *menuScene) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return s.g.screenWidth, s.g.screenHeight
+}
+
+func (s *menuScene) IsOverlay() bool {
+	return s.overlay
+}
+
+func staticLabel(label string) func() string {
+	return func() string { return label }
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// newTitleScene shows the title until Enter is pressed
+func newTitleScene(g *Game) Scene {
+	s := &menuScene{g: g}
+	s.menu = &menu{
+		title: gameTitle,
+		items: []menuItem{
+			{label: staticLabel("Press Enter"), action: func() {
+				g.scenes.fade(func() { g.scenes.replace(newMainMenuScene(g)) })
+			}},
+		},
+	}
+	return s
+}
+
+func newMainMenuScene(g *Game) Scene {
+	s := &menuScene{g: g}
+	s.menu = &menu{
+		title: gameTitle,
+		items: []menuItem{
+			{label: staticLabel("New Game"), action: func() {
+				g.scenes.fade(func() {
+					g.newGame()
+					g.scenes.replace(&gameplayScene{g: g})
+				})
+			}},
+			{label: staticLabel("Load Game"), action: func() { g.scenes.push(newLoadScene(g, false)) }},
+			{label: staticLabel("Options"), action: func() { g.scenes.push(newOptionsScene(g, false)) }},
+			{label: staticLabel("Quit"), action: func() { g.quit = true }},
+		},
+	}
+	return s
+}
+
+// newPauseScene pauses gameplay until it is resumed with Escape or from the menu
+func newPauseScene(g *Game) Scene {
+	s := &menuScene{g: g, overlay: true}
+	s.back = g.scenes.pop
+	s.menu = &menu{
+		title: "Paused",
+		items: []menuItem{
+			{label: staticLabel("Resume"), action: g.scenes.pop},
+			{label: staticLabel("Save Game"), action: func() { g.scenes.push(newSaveScene(g)) }},
+			{label: staticLabel("Load Game"), action: func() { g.scenes.push(newLoadScene(g, true)) }},
+			{label: staticLabel("Options"), action: func() { g.scenes.push(newOptionsScene(g, true)) }},
+			{label: staticLabel("Main Menu"), action: func() {
+				g.scenes.fade(func() { g.scenes.replace(newMainMenuScene(g)) })
+			}},
+			{label: staticLabel("Quit"), action: func() { g.quit = true }},
+		},
+	}
+	return s
+}
+
+// newOptionsScene changes settings, saving them to the config file when leaving
+func newOptionsScene(g *Game, overlay bool) Scene {
+	s := &menuScene{g: g, overlay: overlay}
+	s.back = func() {
+		if err := g.saveConfig(); err != nil {
+			log.Println(err)
+		}
+		g.scenes.pop()
+	}
+
+	toggleInvertY := func() { g.cfg.Input.InvertY = !g.cfg.Input.InvertY }
+	toggleVsync := func() { g.setVsyncEnabled(!g.vsync) }
+	s.menu = &menu{
+		title: "Options",
+		items: []menuItem{
+			{
+				label:  func() string { return fmt.Sprintf("Field of view: %.0f", g.fovDegrees) },
+				adjust: func(dir int) { g.setFovAngle(math.Min(math.Max(g.fovDegrees+5*float64(dir), 40), 120)) },
+			},
+			{
+				label: func() string { return fmt.Sprintf("Mouse sensitivity: %.3f", g.cfg.Input.MouseSensitivity) },
+				adjust: func(dir int) {
+					g.cfg.Input.MouseSensitivity = math.Max(g.cfg.Input.MouseSensitivity+0.001*float64(dir), 0.001)
+				},
+			},
+			{
+				label:  func() string { return "Invert mouse Y: " + onOff(g.cfg.Input.InvertY) },
+				action: toggleInvertY,
+				adjust: func(int) { toggleInvertY() },
+			},
+			{
+				label:  func() string { return fmt.Sprintf("Render scale: %.2f", g.renderScale) },
+				adjust: func(dir int) { g.setRenderScale(math.Min(math.Max(g.renderScale+0.25*float64(dir), 0.25), 1)) },
+			},
+			{
+				label:  func() string { return "VSync: " + onOff(g.vsync) },
+				action: toggleVsync,
+				adjust: func(int) { toggleVsync() },
+			},
+			{label: staticLabel("Back"), action: s.back},
+		},
+	}
+	return s
+}
+
+// newSaveScene saves the game to a numbered slot, returning to the menu below
+func newSaveScene(g *Game) Scene {
+	s := &menuScene{g: g, overlay: true}
+	s.back = g.scenes.pop
+	s.menu = &menu{title: "Save Game"}
+	for n := 1; n <= saveSlots; n++ {
+		slot := slotName(n)
+		s.menu.items = append(s.menu.items, menuItem{
+			label: staticLabel(saveSlotLabel(slot, fmt.Sprintf("Slot %d", n))),
+			action: func() {
+				if err := g.saveToSlot(slot); err != nil {
+					log.Println(err)
+					s.message = "Save failed"
+					return
+				}
+				g.scenes.pop()
+			},
+		})
+	}
+	s.menu.items = append(s.menu.items, menuItem{label: staticLabel("Back"), action: s.back})
+	return s
+}
+
+// newLoadScene loads the game from a numbered slot or the quicksave
+func newLoadScene(g *Game, overlay bool) Scene {
+	s := &menuScene{g: g, overlay: overlay}
+	s.back = g.scenes.pop
+	s.menu = &menu{title: "Load Game"}
+	addSlot := func(slot, title string) {
+		s.menu.items = append(s.menu.items, menuItem{
+			label: staticLabel(saveSlotLabel(slot, title)),
+			action: func() {
+				if err := g.loadFromSlot(slot); err != nil {
+					log.Println(err)
+					s.message = "Could not load the save"
+				}
+			},
+		})
+	}
+	addSlot(quickSaveSlot, "Quicksave")
+	for n := 1; n <= saveSlots; n++ {
+		addSlot(slotName(n), fmt.Sprintf("Slot %d", n))
+	}
+	s.menu.items = append(s.menu.items, menuItem{label: staticLabel("Back"), action: s.back})
+	return s
+}
+
+// newGameOverScene is shown over the view when the player dies
+func newGameOverScene(g *Game) Scene {
+	s := &menuScene{g: g, overlay: true}
+	s.menu = &menu{
+		title: "You died",
+		items: []menuItem{
+			{label: staticLabel("Restart Level"), action: func() {
+				g.scenes.pop()
+				g.restartLevel()
+			}},
+			{label: staticLabel("Load Game"), action: func() { g.scenes.push(newLoadScene(g, true)) }},
+			{label: staticLabel("Main Menu"), action: func() {
+				g.scenes.fade(func() { g.scenes.replace(newMainMenuScene(g)) })
+			}},
+			{label: staticLabel("Quit"), action: func() { g.quit = true }},
+		},
+	}
+	return &gameOverScene{menuScene: s}
+}
+
+// gameOverScene tints the view red behind its menu
+type gameOverScene struct {
+	*menuScene
+}
+
+func (s *gameOverScene) Draw(screen *ebiten.Image) {
+	vector.DrawFilledRect(screen, 0, 0, float32(s.g.screenWidth), float32(s.g.screenHeight), deathOverlayColor, false)
+	s.menu.draw(screen)
+}
+
+// gameplayScene runs the game itself, pausing with Escape, quicksaving with F5 and quickloading with F9
+type gameplayScene struct {
+	g *Game
+}
+
+func (s *gameplayScene) Update() error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		s.g.scenes.push(newPauseScene(s.g))
+		return nil
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+		if err := s.g.saveToSlot(quickSaveSlot); err != nil {
+			log.Println(err)
+			s.g.showMessage("Save failed")
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF9) {
+		if err := s.g.loadFromSlot(quickSaveSlot); err != nil {
+			log.Println(err)
+			s.g.showMessage("No quicksave to load")
+		}
+		return nil
+	}
+	s.g.updateGameplay()
+	return nil
+}
+
+func (s *gameplayScene) Draw(screen *ebiten.Image) {
+	s.g.drawGameplay(screen)
+}
+
+func (s *gameplayScene) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return s.g.screenWidth, s.g.screenHeight
+}
+
+func (s *gameplayScene) IsOverlay() bool {
+	return false
+}
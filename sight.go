@@ -0,0 +1,147 @@
+package main
+
+import (
+	"math"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// rayHit describes where a ray cast across the map stopped
+type rayHit struct {
+	hit      bool    // false if the ray reached its full length or left the map without hitting anything
+	cell     mapCell // cell that was hit
+	level    int     // wall level of the cell that was hit
+	point    geom.Vector2
+	z        float64
+	distance float64
+	side     int // 0 if a side facing along the X axis was hit, 1 for the Y axis, as in the raycaster
+}
+
+// blocksSight checks for a wall or an opaque mid texture in the cell at the given wall level,
+// levels above and below the map never block
+func (m *Map) blocksSight(levelNum, x, y int) bool {
+	if levelNum < 0 || levelNum >= m.zLength {
+		return false
+	}
+	if m.wallMaps[levelNum][x][y] > 0 {
+		return true
+	}
+	mid := m.midMaps[levelNum][x][y]
+	return mid > 0 && m.midTextures[mid].opaque
+}
+
+// castRay steps a ray from (x,y) at height z across the grid cell by cell (DDA), returning the
+// first cell that blocks sight within maxDistance. The ray rises by slope for each unit of
+// distance travelled, so it can pass over walls or hit upper levels. Transparent mid textures
+// do not stop the ray, and the starting cell is never hit.
+func (m *Map) castRay(x, y, z, angle, slope, maxDistance float64) rayHit {
+	dirX, dirY := math.Cos(angle), math.Sin(angle)
+	cellX, cellY := int(math.Floor(x)), int(math.Floor(y))
+
+	// distance along the ray between grid lines, and to the first grid line, on each axis
+	deltaX, deltaY := math.Inf(1), math.Inf(1)
+	stepX, stepY := 1, 1
+	sideDistX, sideDistY := math.Inf(1), math.Inf(1)
+	if dirX != 0 {
+		deltaX = math.Abs(1 / dirX)
+		if dirX < 0 {
+			stepX = -1
+			sideDistX = (x - float64(cellX)) * deltaX
+		} else {
+			sideDistX = (float64(cellX+1) - x) * deltaX
+		}
+	}
+	if dirY != 0 {
+		deltaY = math.Abs(1 / dirY)
+		if dirY < 0 {
+			stepY = -1
+			sideDistY = (y - float64(cellY)) * deltaY
+		} else {
+			sideDistY = (float64(cellY+1) - y) * deltaY
+		}
+	}
+
+	for {
+		var dist float64
+		side := 0
+		if sideDistX < sideDistY {
+			dist = sideDistX
+			sideDistX += deltaX
+			cellX += stepX
+		} else {
+			dist = sideDistY
+			sideDistY += deltaY
+			cellY += stepY
+			side = 1
+		}
+
+		if dist > maxDistance || cellX < 0 || cellY < 0 || cellX >= m.xLength || cellY >= m.yLength {
+			d := math.Min(dist, maxDistance)
+			return rayHit{point: geom.Vector2{X: x + dirX*d, Y: y + dirY*d}, z: z + slope*d, distance: d}
+		}
+
+		// check every level the ray passes through while crossing the cell
+		exitDist := math.Min(math.Min(sideDistX, sideDistY), maxDistance)
+		zEnter, zExit := z+slope*dist, z+slope*exitDist
+		minLevel := int(math.Floor(math.Min(zEnter, zExit)))
+		maxLevel := int(math.Floor(math.Max(zEnter, zExit)))
+		first, last, step := minLevel, maxLevel, 1
+		if slope < 0 {
+			// a falling ray meets the upper levels first
+			first, last, step = maxLevel, minLevel, -1
+		}
+
+		for level := first; level != last+step; level += step {
+			if m.blocksSight(level, cellX, cellY) {
+				return rayHit{
+					hit:      true,
+					cell:     mapCell{x: cellX, y: cellY},
+					level:    level,
+					point:    geom.Vector2{X: x + dirX*dist, Y: y + dirY*dist},
+					z:        zEnter,
+					distance: dist,
+					side:     side,
+				}
+			}
+		}
+	}
+}
+
+// hasLineOfSight returns true if nothing blocks the view between two points at the given heights
+func (m *Map) hasLineOfSight(a *geom.Vector2, aZ float64, b *geom.Vector2, bZ float64) bool {
+	dist := geom.Distance(a.X, a.Y, b.X, b.Y)
+	if dist == 0 {
+		return true
+	}
+	angle := math.Atan2(b.Y-a.Y, b.X-a.X)
+	return !m.castRay(a.X, a.Y, aZ, angle, (bZ-aZ)/dist, dist).hit
+}
+
+// inView returns true if the target is within the viewRange and field of view (in radians) of
+// an observer looking along angle, with nothing blocking its line of sight
+func (m *Map) inView(pos *geom.Vector2, z, angle, fov, viewRange float64, target *geom.Vector2, targetZ float64) bool {
+	dist := geom.Distance(pos.X, pos.Y, target.X, target.Y)
+	if dist > viewRange {
+		return false
+	}
+	if dist > 0 && fov < geom.Pi2 {
+		// smallest angle between the view direction and the target, either way around
+		diff := math.Mod(math.Abs(math.Atan2(target.Y-pos.Y, target.X-pos.X)-angle), geom.Pi2)
+		if math.Min(diff, geom.Pi2-diff) > fov/2 {
+			return false
+		}
+	}
+	return m.hasLineOfSight(pos, z, target, targetZ)
+}
+
+// entityInView returns true if the observer, looking along its heading with the given field of
+// view (in radians) and range, can see the target with nothing blocking the view between the
+// middles of the two
+func (m *Map) entityInView(observer *Entity, fov, viewRange float64, target *Entity) bool {
+	return m.inView(observer.Position, observer.centerZ(), observer.Angle, fov, viewRange, target.Position, target.centerZ())
+}
+
+// centerZ returns the height of the middle of the entity
+func (e *Entity) centerZ() float64 {
+	return e.PositionZ + e.CollisionHeight/2
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// sightMap builds a 10x10 map with two wall levels and no boundary walls:
+//
+//	(5,1) wall, (1,6) wall, (6,3) wall behind a transparent mid at (3,3), an opaque mid at (3,5),
+//	a low wall at (2,8) in front of an upper level wall at (4,8)
+func sightMap() *Map {
+	const size, levels = 10, 2
+	grid := func() [][][]int {
+		g := make([][][]int, levels)
+		for z := range g {
+			g[z] = make([][]int, size)
+			for x := range g[z] {
+				g[z][x] = make([]int, size)
+			}
+		}
+		return g
+	}
+	m := &Map{
+		wallMaps: grid(),
+		midMaps:  grid(),
+		xLength:  size,
+		yLength:  size,
+		zLength:  levels,
+		midTextures: map[int]midTexture{
+			1: {name: "fence"},
+			2: {name: "curtain", opaque: true},
+		},
+	}
+	m.wallMaps[0][5][1] = 1
+	m.wallMaps[0][1][6] = 1
+	m.wallMaps[0][6][3] = 1
+	m.midMaps[0][3][3] = 1
+	m.midMaps[0][3][5] = 2
+	m.wallMaps[0][2][8] = 1
+	m.wallMaps[1][4][8] = 1
+	return m
+}
+
+func TestCastRay(t *testing.T) {
+	m := sightMap()
+	const eps = 1e-9
+
+	tests := []struct {
+		name         string
+		x, y, z      float64
+		angle, slope float64
+		maxDistance  float64
+		want         rayHit
+	}{
+		{
+			name: "along x", x: 1.5, y: 1.5, z: 0.5, angle: 0, maxDistance: 20,
+			want: rayHit{hit: true, cell: mapCell{5, 1}, point: geom.Vector2{X: 5, Y: 1.5}, z: 0.5, distance: 3.5, side: 0},
+		},
+		{
+			name: "along y", x: 1.5, y: 1.5, z: 0.5, angle: math.Pi / 2, maxDistance: 20,
+			want: rayHit{hit: true, cell: mapCell{1, 6}, point: geom.Vector2{X: 1.5, Y: 6}, z: 0.5, distance: 4.5, side: 1},
+		},
+		{
+			name: "backwards along x", x: 8.5, y: 1.5, z: 0.5, angle: math.Pi, maxDistance: 20,
+			want: rayHit{hit: true, cell: mapCell{5, 1}, point: geom.Vector2{X: 6, Y: 1.5}, z: 0.5, distance: 2.5, side: 0},
+		},
+		{
+			name: "diagonal", x: 4.5, y: 0.25, z: 0.5, angle: math.Pi / 4, maxDistance: 20,
+			// crosses into (5,0) first, then meets (5,1) on its y side
+			want: rayHit{hit: true, cell: mapCell{5, 1}, point: geom.Vector2{X: 5.25, Y: 1}, z: 0.5, distance: 0.75 * math.Sqrt2, side: 1},
+		},
+		{
+			name: "short of the wall", x: 1.5, y: 1.5, z: 0.5, angle: 0, maxDistance: 2,
+			want: rayHit{point: geom.Vector2{X: 3.5, Y: 1.5}, z: 0.5, distance: 2},
+		},
+		{
+			name: "leaves the map", x: 8.5, y: 6.5, z: 0.5, angle: 0, maxDistance: 20,
+			want: rayHit{point: geom.Vector2{X: 10, Y: 6.5}, z: 0.5, distance: 1.5},
+		},
+		{
+			name: "through transparent mid", x: 0.5, y: 3.5, z: 0.5, angle: 0, maxDistance: 20,
+			want: rayHit{hit: true, cell: mapCell{6, 3}, point: geom.Vector2{X: 6, Y: 3.5}, z: 0.5, distance: 5.5, side: 0},
+		},
+		{
+			name: "stopped by opaque mid", x: 0.5, y: 5.5, z: 0.5, angle: 0, maxDistance: 20,
+			want: rayHit{hit: true, cell: mapCell{3, 5}, point: geom.Vector2{X: 3, Y: 5.5}, z: 0.5, distance: 2.5, side: 0},
+		},
+		{
+			name: "level into low wall", x: 0.5, y: 8.5, z: 0.5, angle: 0, maxDistance: 20,
+			want: rayHit{hit: true, cell: mapCell{2, 8}, point: geom.Vector2{X: 2, Y: 8.5}, z: 0.5, distance: 1.5, side: 0},
+		},
+		{
+			name: "over low wall into upper level", x: 0.5, y: 8.5, z: 0.5, angle: 0, slope: 0.4, maxDistance: 20,
+			want: rayHit{hit: true, cell: mapCell{4, 8}, level: 1, point: geom.Vector2{X: 4, Y: 8.5}, z: 1.9, distance: 3.5, side: 0},
+		},
+		{
+			name: "falling onto low wall", x: 0.5, y: 8.5, z: 1.5, angle: 0, slope: -0.4, maxDistance: 20,
+			want: rayHit{hit: true, cell: mapCell{2, 8}, point: geom.Vector2{X: 2, Y: 8.5}, z: 0.9, distance: 1.5, side: 0},
+		},
+		{
+			name: "over everything", x: 0.5, y: 8.5, z: 1.5, angle: 0, slope: 0.4, maxDistance: 20,
+			want: rayHit{point: geom.Vector2{X: 10, Y: 8.5}, z: 5.3, distance: 9.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.castRay(tt.x, tt.y, tt.z, tt.angle, tt.slope, tt.maxDistance)
+			if got.hit != tt.want.hit || got.cell != tt.want.cell || got.level != tt.want.level || got.side != tt.want.side ||
+				math.Abs(got.distance-tt.want.distance) > eps || math.Abs(got.z-tt.want.z) > eps ||
+				math.Abs(got.point.X-tt.want.point.X) > eps || math.Abs(got.point.Y-tt.want.point.Y) > eps {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasLineOfSight(t *testing.T) {
+	m := sightMap()
+	from := &geom.Vector2{X: 0.5, Y: 8.5}
+	to := &geom.Vector2{X: 3.5, Y: 8.5}
+
+	if m.hasLineOfSight(from, 0.5, to, 0.5) {
+		t.Error("saw through the low wall")
+	}
+	if !m.hasLineOfSight(from, 1.5, to, 1.5) {
+		t.Error("could not see over the low wall")
+	}
+	if !m.hasLineOfSight(from, 0.5, from, 0.5) {
+		t.Error("could not see own position")
+	}
+}
+
+func TestInViewCone(t *testing.T) {
+	m := sightMap()
+	pos := &geom.Vector2{X: 7.5, Y: 7.5}
+	const fov, viewRange = math.Pi / 2, 2
+	const eps = 0.01
+
+	// target one cell away in the given direction
+	at := func(angle float64) *geom.Vector2 {
+		return &geom.Vector2{X: pos.X + math.Cos(angle), Y: pos.Y + math.Sin(angle)}
+	}
+
+	tests := []struct {
+		heading, target float64
+		fov             float64
+		want            bool
+	}{
+		{0, 0, fov, true},
+		{0, fov/2 - eps, fov, true},
+		{0, fov/2 + eps, fov, false},
+		{0, -fov/2 + eps, fov, true},
+		{0, -fov/2 - eps, fov, false},
+		{0, math.Pi, fov, false},
+		// the cone wraps around zero
+		{geom.Pi2 - eps, fov/2 - 2*eps, fov, true},
+		{geom.Pi2 - eps, -fov/2 + eps, fov, true},
+		{geom.Pi2 - eps, -fov/2 - 2*eps, fov, false},
+		{eps, geom.Pi2 - fov/2 + 2*eps, fov, true},
+		{-math.Pi / 2, 3*math.Pi/2 + fov/2 - eps, fov, true},
+		{-math.Pi / 2, 0, fov, false},
+		// all the way around sees behind
+		{0, math.Pi, geom.Pi2, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("heading %.3f target %.3f fov %.3f", tt.heading, tt.target, tt.fov), func(t *testing.T) {
+			if got := m.inView(pos, 0.5, tt.heading, tt.fov, viewRange, at(tt.target), 0.5); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	far := &geom.Vector2{X: pos.X + viewRange + eps, Y: pos.Y}
+	if m.inView(pos, 0.5, 0, fov, viewRange, far, 0.5) {
+		t.Error("saw a target out of range")
+	}
+	// in the cone and in range, but behind the opaque mid
+	if m.inView(&geom.Vector2{X: 4.5, Y: 5.5}, 0.5, math.Pi, fov, viewRange, &geom.Vector2{X: 2.5, Y: 5.5}, 0.5) {
+		t.Error("saw through the opaque mid")
+	}
+}
+
+func TestEntityInView(t *testing.T) {
+	m := sightMap()
+	entity := func(x, y, z, angle float64) *Entity {
+		return &Entity{Position: &geom.Vector2{X: x, Y: y}, PositionZ: z, Angle: angle, CollisionHeight: 1}
+	}
+	const fov, viewRange = math.Pi / 2, 5
+
+	tests := []struct {
+		name             string
+		observer, target *Entity
+		want             bool
+	}{
+		{"facing across open floor", entity(7.5, 7.5, 0, 0), entity(9.5, 7.5, 0, 0), true},
+		{"facing away", entity(7.5, 7.5, 0, math.Pi), entity(9.5, 7.5, 0, 0), false},
+		{"out of range", entity(0.5, 0.5, 0, 0), entity(7.5, 0.5, 0, 0), false},
+		{"behind the low wall", entity(0.5, 8.5, 0, 0), entity(3.5, 8.5, 0, 0), false},
+		{"both above the low wall", entity(0.5, 8.5, 1, 0), entity(3.5, 8.5, 1, 0), true},
+		{"looking down past the low wall", entity(0.5, 8.5, 1, 0), entity(3.5, 8.5, 0, 0), false},
+		{"through the fence", entity(3.5, 1.5, 0, math.Pi/2), entity(3.5, 4.5, 0, 0), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.entityInView(tt.observer, fov, viewRange, tt.target); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
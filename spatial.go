@@ -0,0 +1,179 @@
+package main
+
+import (
+	"math"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// spatialGrid buckets wall collision lines and sprites by the map cells they overlap so that
+// collision queries only need to look at what is near the area being checked
+type spatialGrid struct {
+	bounds      *lineIndex
+	walls       []*lineIndex // one per wall level
+	spriteCells map[mapCell]map[*Sprite]struct{}
+	spriteKeys  map[*Sprite][]mapCell
+}
+
+// lineIndex buckets a set of collision lines by cell
+type lineIndex struct {
+	lines      []geom.Line
+	lineCells  map[mapCell][]int
+	lineStamps []int
+	queryStamp int
+}
+
+func newSpatialGrid(bounds []geom.Line, walls [][]geom.Line) *spatialGrid {
+	sg := &spatialGrid{
+		bounds:      newLineIndex(bounds),
+		walls:       make([]*lineIndex, len(walls)),
+		spriteCells: make(map[mapCell]map[*Sprite]struct{}),
+		spriteKeys:  make(map[*Sprite][]mapCell),
+	}
+	for z, lines := range walls {
+		sg.walls[z] = newLineIndex(lines)
+	}
+	return sg
+}
+
+func newLineIndex(lines []geom.Line) *lineIndex {
+	li := &lineIndex{
+		lines:      lines,
+		lineCells:  make(map[mapCell][]int),
+		lineStamps: make([]int, len(lines)),
+	}
+
+	for i, line := range lines {
+		minX, maxX := math.Min(line.X1, line.X2), math.Max(line.X1, line.X2)
+		minY, maxY := math.Min(line.Y1, line.Y2), math.Max(line.Y1, line.Y2)
+		for _, cell := range cellsInBounds(minX, minY, maxX, maxY) {
+			li.lineCells[cell] = append(li.lineCells[cell], i)
+		}
+	}
+
+	return li
+}
+
+// cellsInBounds returns every cell overlapped by the given bounding box
+func cellsInBounds(minX, minY, maxX, maxY float64) []mapCell {
+	x0, y0 := int(math.Floor(minX)), int(math.Floor(minY))
+	x1, y1 := int(math.Floor(maxX)), int(math.Floor(maxY))
+
+	cells := make([]mapCell, 0, (x1-x0+1)*(y1-y0+1))
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			cells = append(cells, mapCell{x: x, y: y})
+		}
+	}
+	return cells
+}
+
+// near appends the lines overlapping the given bounding box, each at most once
+func (li *lineIndex) near(lines []geom.Line, minX, minY, maxX, maxY float64) []geom.Line {
+	li.queryStamp++
+	for _, cell := range cellsInBounds(minX, minY, maxX, maxY) {
+		for _, i := range li.lineCells[cell] {
+			if li.lineStamps[i] == li.queryStamp {
+				continue
+			}
+			li.lineStamps[i] = li.queryStamp
+			lines = append(lines, li.lines[i])
+		}
+	}
+	return lines
+}
+
+// spritesNear returns the sprites whose collision circles overlap the given bounding box
+func (sg *spatialGrid) spritesNear(minX, minY, maxX, maxY float64) []*Sprite {
+	var sprites []*Sprite
+	found := make(map[*Sprite]struct{})
+	for _, cell := range cellsInBounds(minX, minY, maxX, maxY) {
+		for sprite := range sg.spriteCells[cell] {
+			if _, ok := found[sprite]; ok {
+				continue
+			}
+			found[sprite] = struct{}{}
+			sprites = append(sprites, sprite)
+		}
+	}
+	return sprites
+}
+
+// updateSprite moves the sprite into the cells covered by its current collision circle
+func (sg *spatialGrid) updateSprite(s *Sprite) {
+	sg.removeSprite(s)
+
+	r := s.CollisionRadius
+	cells := cellsInBounds(s.Position.X-r, s.Position.Y-r, s.Position.X+r, s.Position.Y+r)
+	for _, cell := range cells {
+		bucket, ok := sg.spriteCells[cell]
+		if !ok {
+			bucket = make(map[*Sprite]struct{})
+			sg.spriteCells[cell] = bucket
+		}
+		bucket[s] = struct{}{}
+	}
+	sg.spriteKeys[s] = cells
+}
+
+func (sg *spatialGrid) removeSprite(s *Sprite) {
+	for _, cell := range sg.spriteKeys[s] {
+		bucket := sg.spriteCells[cell]
+		delete(bucket, s)
+		if len(bucket) == 0 {
+			delete(sg.spriteCells, cell)
+		}
+	}
+	delete(sg.spriteKeys, s)
+}
+
+func (sg *spatialGrid) clearSprites() {
+	sg.spriteCells = make(map[mapCell]map[*Sprite]struct{})
+	sg.spriteKeys = make(map[*Sprite][]mapCell)
+}
+
+// linesNear returns the map boundary lines and the collision lines of every wall level
+// between minZ and maxZ that are near the bounding box
+func (m *Map) linesNear(minX, minY, maxX, maxY, minZ, maxZ float64) []geom.Line {
+	lines := m.grid.bounds.near([]geom.Line{}, minX, minY, maxX, maxY)
+	for _, z := range m.levelsSpanned(minZ, maxZ) {
+		lines = m.grid.walls[z].near(lines, minX, minY, maxX, maxY)
+	}
+	return lines
+}
+
+// levelLinesNear returns the collision lines of a single wall level near the bounding box
+func (m *Map) levelLinesNear(levelNum int, minX, minY, maxX, maxY float64) []geom.Line {
+	return m.grid.walls[levelNum].near([]geom.Line{}, minX, minY, maxX, maxY)
+}
+
+// boundsNear returns the map boundary lines near the bounding box
+func (m *Map) boundsNear(minX, minY, maxX, maxY float64) []geom.Line {
+	return m.grid.bounds.near([]geom.Line{}, minX, minY, maxX, maxY)
+}
+
+// spritesNear returns the sprites near the bounding box
+func (m *Map) spritesNear(minX, minY, maxX, maxY float64) []*Sprite {
+	return m.grid.spritesNear(minX, minY, maxX, maxY)
+}
+
+// spritesNearCircle returns the sprites near a circle around the given position
+func (m *Map) spritesNearCircle(pos *geom.Vector2, radius float64) []*Sprite {
+	return m.grid.spritesNear(pos.X-radius, pos.Y-radius, pos.X+radius, pos.Y+radius)
+}
+
+// moveSprite updates the sprite's position and its place in the spatial index
+func (m *Map) moveSprite(s *Sprite, pos *geom.Vector2) {
+	s.Position = pos
+	m.grid.updateSprite(s)
+}
+
+func (m *Map) removeSprite(s *Sprite) {
+	delete(m.sprites, s)
+	m.grid.removeSprite(s)
+}
+
+func (m *Map) clearSprites() {
+	m.sprites = make(map[*Sprite]struct{}, 128)
+	m.grid.clearSprites()
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+const (
+	benchMapSize = 256
+	benchSprites = 500
+	benchMoves   = 1024
+)
+
+// benchMap builds a large walled map with a pillar every few cells and hundreds of sprites
+// scattered between them
+func benchMap(b *testing.B) *Map {
+	b.Helper()
+	var sb strings.Builder
+	sb.WriteString("walltexture 1 stone\nwalls\n")
+	for x := 0; x < benchMapSize; x++ {
+		for y := 0; y < benchMapSize; y++ {
+			edge := x == 0 || y == 0 || x == benchMapSize-1 || y == benchMapSize-1
+			pillar := x%8 == 4 && y%8 == 4
+			if edge || pillar {
+				sb.WriteString("1")
+			} else {
+				sb.WriteString("0")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("end\n")
+
+	m, err := parseMap(strings.NewReader(sb.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	m.clearSprites()
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < benchSprites; i++ {
+		x, y := benchOpenPosition(m, rng)
+		m.addSprite(&Sprite{Entity: &Entity{
+			Position:        &geom.Vector2{X: x, Y: y},
+			CollisionRadius: 0.2,
+			CollisionHeight: 0.5,
+		}})
+	}
+	return m
+}
+
+func benchOpenPosition(m *Map, rng *rand.Rand) (float64, float64) {
+	for {
+		x := 1 + rng.Float64()*float64(m.xLength-2)
+		y := 1 + rng.Float64()*float64(m.yLength-2)
+		if m.isOpenCell(x, y) {
+			return x, y
+		}
+	}
+}
+
+// benchMoveLines returns short moves in random directions from open positions, like a tick of
+// walking
+func benchMoveLines(m *Map) []geom.Line {
+	rng := rand.New(rand.NewSource(2))
+	moves := make([]geom.Line, benchMoves)
+	for i := range moves {
+		x, y := benchOpenPosition(m, rng)
+		moves[i] = geom.LineFromAngle(x, y, rng.Float64()*geom.Pi2, 0.1)
+	}
+	return moves
+}
+
+func moveBounds(l geom.Line) (minX, minY, maxX, maxY float64) {
+	return math.Min(l.X1, l.X2), math.Min(l.Y1, l.Y2), math.Max(l.X1, l.X2), math.Max(l.Y1, l.Y2)
+}
+
+// the scan benchmarks check every line or sprite in the map as collisions did before the
+// spatial grid
+func BenchmarkLinesNear(b *testing.B) {
+	m := benchMap(b)
+	moves := benchMoveLines(m)
+
+	b.Run("grid", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			move := moves[i%len(moves)]
+			minX, minY, maxX, maxY := moveBounds(move)
+			for _, line := range m.linesNear(minX, minY, maxX, maxY, 0, 0.5) {
+				geom.LineIntersection(move, line)
+			}
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			move := moves[i%len(moves)]
+			for _, line := range m.boundaryLines {
+				geom.LineIntersection(move, line)
+			}
+			for _, line := range m.collisionMaps[0] {
+				geom.LineIntersection(move, line)
+			}
+		}
+	})
+}
+
+func BenchmarkSpritesNear(b *testing.B) {
+	m := benchMap(b)
+	moves := benchMoveLines(m)
+	const r = 0.2
+
+	checkSprite := func(move geom.Line, s *Sprite) {
+		circle := geom.Circle{X: s.Position.X, Y: s.Position.Y, Radius: s.CollisionRadius + r}
+		geom.LineCircleIntersection(move, circle, true)
+	}
+
+	b.Run("grid", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			move := moves[i%len(moves)]
+			minX, minY, maxX, maxY := moveBounds(move)
+			for _, s := range m.spritesNear(minX-r, minY-r, maxX+r, maxY+r) {
+				checkSprite(move, s)
+			}
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			move := moves[i%len(moves)]
+			for s := range m.sprites {
+				checkSprite(move, s)
+			}
+		}
+	})
+}
+
+func BenchmarkGetValidMove(b *testing.B) {
+	m := benchMap(b)
+	moves := benchMoveLines(m)
+	g := &Game{gameLevels: &gameLevels{levelMaps: []*Map{m}}}
+	g.player = newSpawnedPlayer(m)
+
+	for _, checkAlternate := range []bool{false, true} {
+		b.Run(fmt.Sprintf("checkAlternate=%v", checkAlternate), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				move := moves[i%len(moves)]
+				g.player.Position.X, g.player.Position.Y = move.X1, move.Y1
+				g.getValidMove(g.player.Entity, move.X2, move.Y2, 0, checkAlternate)
+			}
+		})
+	}
+}
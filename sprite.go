@@ -21,9 +21,13 @@ type Entity struct {
 	Anchor          raycaster.SpriteAnchor
 	Angle           float64
 	Pitch           float64
-	Velocity        float64
+	Velocity        float64 // map units per second
+	VelocityZ       float64 // map units per second upwards
 	CollisionRadius float64
 	CollisionHeight float64
+	Health          float64
+	MaxHealth       float64
+	Armor           float64
 	MapColor        color.RGBA
 	Parent          *Entity
 }
@@ -39,11 +43,15 @@ func (e *Entity) PosZ() float64 {
 type Sprite struct {
 	*Entity
 	W, H           int
-	AnimationRate  int
+	AnimationRate  float64 // frames per second, 0 is not animated
 	Focusable      bool
+	Interaction    *Interaction
+	Key            string // name of the key given to the player when picked up
+	OnClipDone     ClipDoneFunc
+	AI             *AI // moves the sprite on its own when set
 	illumination   float64
 	animReversed   bool
-	animCounter    int
+	animTime       float64 // seconds the current frame has been shown
 	loopCounter    int
 	columns, rows  int
 	texNum, lenTex int
@@ -52,6 +60,11 @@ type Sprite struct {
 	texRects       []image.Rectangle
 	textures       []*ebiten.Image
 	screenRect     *image.Rectangle
+	clips          map[string]*AnimationClip
+	clip           *AnimationClip
+	clipFrame      int
+	clipStep       int
+	clipDone       bool
 }
 
 func (s *Sprite) Scale() float64 {
@@ -161,7 +174,7 @@ func NewSpriteFromSheet(
 }
 
 func NewAnimatedSprite(
-	x, y, scale float64, animationRate int, img *ebiten.Image, mapColor color.RGBA,
+	x, y, scale, animationRate float64, img *ebiten.Image, mapColor color.RGBA,
 	columns, rows int, anchor raycaster.SpriteAnchor, collisionRadius, collisionHeight float64,
 ) *Sprite {
 	s := &Sprite{
@@ -180,7 +193,7 @@ func NewAnimatedSprite(
 	}
 
 	s.AnimationRate = animationRate
-	s.animCounter = 0
+	s.animTime = 0
 	s.loopCounter = 0
 
 	s.texNum = 0
@@ -215,22 +228,40 @@ func (s *Sprite) SetTextureFacingMap(texFacingMap map[float64]int) {
 	s.texFacingMap = texFacingMap
 
 	// create pre-sorted list of keys used during facing determination
-	s.texFacingKeys = make([]float64, len(texFacingMap))
+	s.texFacingKeys = make([]float64, 0, len(texFacingMap))
 	for k := range texFacingMap {
 		s.texFacingKeys = append(s.texFacingKeys, k)
 	}
 	sort.Float64s(s.texFacingKeys)
 }
 
+// SetFacingDirections sets up the facing map for a sheet with one row per direction, such as
+// the common 4, 8 or 16 direction sheets. Row 0 is seen from directly in front of the sprite
+// and each following row is seen from an equal step further counter-clockwise around it.
+func (s *Sprite) SetFacingDirections(directions int) {
+	s.SetTextureFacingMap(facingDirections(directions))
+}
+
+// facingDirections returns a facing map of evenly spaced angles, one row per direction
+func facingDirections(directions int) map[float64]int {
+	texFacingMap := make(map[float64]int, directions)
+	for row := 0; row < directions; row++ {
+		texFacingMap[geom.Pi2*float64(row)/float64(directions)] = row
+	}
+	return texFacingMap
+}
+
 func (s *Sprite) getTextureFacingKeyForAngle(facingAngle float64) float64 {
 	var closestKeyAngle float64 = -1
-	if s.texFacingMap == nil || len(s.texFacingMap) == 0 || s.texFacingKeys == nil || len(s.texFacingKeys) == 0 {
+	if len(s.texFacingKeys) == 0 {
 		return closestKeyAngle
 	}
 
 	closestKeyDiff := math.MaxFloat64
 	for _, keyAngle := range s.texFacingKeys {
-		keyDiff := math.Min(geom.Pi2-math.Abs(float64(keyAngle)-facingAngle), math.Abs(float64(keyAngle)-facingAngle))
+		// compare around the circle so that keys on either side of 0 are equally close
+		keyDiff := math.Mod(math.Abs(keyAngle-facingAngle), geom.Pi2)
+		keyDiff = math.Min(keyDiff, geom.Pi2-keyDiff)
 		if keyDiff < closestKeyDiff {
 			closestKeyDiff = keyDiff
 			closestKeyAngle = keyAngle
@@ -249,9 +280,12 @@ func (s *Sprite) SetAnimationFrame(texNum int) {
 }
 
 func (s *Sprite) ResetAnimation() {
-	s.animCounter = 0
+	s.animTime = 0
 	s.loopCounter = 0
 	s.texNum = 0
+	if s.clip != nil {
+		s.clipFrame, s.clipStep, s.clipDone = s.clip.First, 1, false
+	}
 }
 
 func (s *Sprite) LoopCounter() int {
@@ -262,40 +296,29 @@ func (s *Sprite) ScreenRect() *image.Rectangle {
 	return s.screenRect
 }
 
-func (s *Sprite) Update(camPos *geom.Vector2) {
-	if s.AnimationRate <= 0 {
+// Update turns the sprite's frame to face the camera and advances its animation by dt seconds
+func (s *Sprite) Update(camPos *geom.Vector2, dt float64) {
+	if s.clip != nil {
+		s.updateClip(camPos, dt)
 		return
 	}
 
-	if s.animCounter >= s.AnimationRate {
+	// facing is kept up to date every tick, not only when the animation frame changes
+	texRow := s.facingRow(camPos)
+	if len(s.texFacingMap) > 0 && s.columns > 0 {
+		s.texNum = texRow*s.columns + s.texNum%s.columns
+	}
+
+	for steps := s.animationSteps(dt, s.AnimationRate); steps > 0; steps-- {
 		minTexNum := 0
 		maxTexNum := s.lenTex - 1
 
-		if len(s.texFacingMap) > 1 && camPos != nil {
-			// TODO: may want to be able to change facing even between animation frame changes
-
-			// use facing from camera position to determine min/max texNum in texFacingMap
-			// to update facing of sprite relative to camera and sprite angle
-			texRow := 0
-
-			// calculate angle from sprite relative to camera position by getting angle of line between them
-			lineToCam := geom.Line{X1: s.Position.X, Y1: s.Position.Y, X2: camPos.X, Y2: camPos.Y}
-			facingAngle := lineToCam.Angle() - s.Angle
-			if facingAngle < 0 {
-				// convert to positive angle needed to determine facing index to use
-				facingAngle += geom.Pi2
-			}
-			facingKeyAngle := s.getTextureFacingKeyForAngle(facingAngle)
-			if texFacingValue, ok := s.texFacingMap[facingKeyAngle]; ok {
-				texRow = texFacingValue
-			}
-
+		if len(s.texFacingMap) > 0 && s.columns > 0 {
+			// animate within the row for the current facing
 			minTexNum = texRow * s.columns
 			maxTexNum = texRow*s.columns + s.columns - 1
 		}
 
-		s.animCounter = 0
-
 		if s.animReversed {
 			s.texNum -= 1
 			if s.texNum > maxTexNum || s.texNum < minTexNum {
@@ -309,11 +332,44 @@ func (s *Sprite) Update(camPos *geom.Vector2) {
 				s.loopCounter++
 			}
 		}
-	} else {
-		s.animCounter++
 	}
 }
 
+// animationSteps adds dt seconds to the time the current frame has been shown, returning how
+// many frames to step at the given frames per second
+func (s *Sprite) animationSteps(dt, fps float64) int {
+	if fps <= 0 {
+		return 0
+	}
+	s.animTime += dt
+	// allow for rounding so that frames lasting a whole number of ticks are not held a tick longer
+	steps := int(s.animTime*fps + 1e-9)
+	s.animTime = math.Max(s.animTime-float64(steps)/fps, 0)
+	return steps
+}
+
+// facingRow returns the sheet row in texFacingMap for the sprite as seen from the camera position,
+// or 0 if the sprite has no facing map
+func (s *Sprite) facingRow(camPos *geom.Vector2) int {
+	if len(s.texFacingMap) == 0 || camPos == nil {
+		return 0
+	}
+
+	// calculate angle from sprite relative to camera position by getting angle of line between them
+	lineToCam := geom.Line{X1: s.Position.X, Y1: s.Position.Y, X2: camPos.X, Y2: camPos.Y}
+	facingAngle := math.Mod(lineToCam.Angle()-s.Angle, geom.Pi2)
+	if facingAngle < 0 {
+		// convert to positive angle needed to determine facing index to use
+		facingAngle += geom.Pi2
+	}
+
+	facingKeyAngle := s.getTextureFacingKeyForAngle(facingAngle)
+	if texFacingValue, ok := s.texFacingMap[facingKeyAngle]; ok && texFacingValue < s.rows {
+		return texFacingValue
+	}
+	return 0
+}
+
 func (s *Sprite) AddDebugLines(lineWidth int, clr color.Color) {
 	lW := float64(lineWidth)
 	sW := float64(s.W)
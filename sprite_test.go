@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// facingSprite returns a sprite at the origin with a sheet of one row per facing direction
+func facingSprite(directions int, heading float64) *Sprite {
+	s := &Sprite{Entity: &Entity{Position: &geom.Vector2{}, Angle: heading}}
+	s.columns, s.rows = 1, directions
+	s.SetFacingDirections(directions)
+	return s
+}
+
+func TestTextureFacingKeyForAngle(t *testing.T) {
+	const eps = 1e-6
+	tests := []struct {
+		directions int
+		angle      float64
+		wantRow    int
+	}{
+		{4, 0, 0},
+		{4, eps, 0},
+		{4, geom.Pi2 - eps, 0},
+		{4, geom.Pi2, 0},
+		{4, -eps, 0},
+		{4, -geom.Pi2 / 4, 3},
+		{4, geom.Pi2/8 - eps, 0},
+		{4, geom.Pi2/8 + eps, 1},
+		{4, geom.Pi2*7/8 + eps, 0},
+		{8, geom.Pi2 - eps, 0},
+		{8, geom.Pi2/16 - eps, 0},
+		{8, geom.Pi2/16 + eps, 1},
+		{8, -geom.Pi2 / 8, 7},
+		{16, geom.Pi2*31/32 + eps, 0},
+		{16, geom.Pi2*31/32 - eps, 15},
+		{16, geom.Pi2 / 2, 8},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%.6f", tt.directions, tt.angle), func(t *testing.T) {
+			s := facingSprite(tt.directions, 0)
+			key := s.getTextureFacingKeyForAngle(tt.angle)
+			if got, ok := s.texFacingMap[key]; !ok || got != tt.wantRow {
+				t.Errorf("got key %v for row %d, want row %d", key, got, tt.wantRow)
+			}
+		})
+	}
+
+	if got := (&Sprite{}).getTextureFacingKeyForAngle(1); got != -1 {
+		t.Errorf("got key %v without a facing map, want -1", got)
+	}
+}
+
+func TestFacingRow(t *testing.T) {
+	const eps = 1e-3
+	headings := []float64{0, eps, geom.Pi2 - eps, -eps, -geom.Pi2 / 4, geom.Pi2 * 5 / 4}
+
+	for _, directions := range []int{4, 8, 16} {
+		step := geom.Pi2 / float64(directions)
+		for _, heading := range headings {
+			s := facingSprite(directions, heading)
+			for row := 0; row < directions; row++ {
+				// anywhere within half a step either side of a row's direction shows that row
+				for _, offset := range []float64{-step/2 + eps, 0, step/2 - eps} {
+					around := heading + float64(row)*step + offset
+					camPos := &geom.Vector2{X: math.Cos(around), Y: math.Sin(around)}
+					if got := s.facingRow(camPos); got != row {
+						t.Errorf("%d directions, heading %.4f, camera at %.4f: got row %d, want %d",
+							directions, heading, around, got, row)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFacingRowSeenFrom(t *testing.T) {
+	// row 0 from in front, following rows counter-clockwise around the sprite
+	s := facingSprite(4, 0)
+	for _, tt := range []struct {
+		camPos geom.Vector2
+		want   int
+	}{
+		{geom.Vector2{X: 3, Y: 0}, 0},
+		{geom.Vector2{X: 0, Y: 3}, 1},
+		{geom.Vector2{X: -3, Y: 0}, 2},
+		{geom.Vector2{X: 0, Y: -3}, 3},
+		{geom.Vector2{X: 3, Y: -0.01}, 0},
+	} {
+		if got := s.facingRow(&tt.camPos); got != tt.want {
+			t.Errorf("camera at %v: got row %d, want %d", tt.camPos, got, tt.want)
+		}
+	}
+
+	if got := facingSprite(4, 0).facingRow(nil); got != 0 {
+		t.Errorf("got row %d without a camera, want 0", got)
+	}
+}
@@ -1,82 +1,128 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"image"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
-const (
-	numFloorAndCeilingTextures = 9
-	numWallTextures            = 5
-	numSpriteTextures          = 5
-)
-
 type TextureHandler struct {
-	gameLevels              *gameLevels
-	wallTextures            []*ebiten.Image
-	spriteTextures          []*ebiten.Image
-	floorAndCeilingTextures []*image.RGBA
+	gameLevels    *gameLevels
+	registry      *textureRegistry
+	archetypes    map[string]*spriteArchetype
+	levelTextures []*levelTextures // one per game level
+	doorFrames    map[*Door]*doorFrame
 }
 
-func NewTextureHandler(gameLevels *gameLevels) *TextureHandler {
+// NewTextureHandler loads the textures in the manifest and the sprite archetypes, and resolves
+// the texture and archetype names used by every level, returning all missing or unknown names
+// as a single error
+func NewTextureHandler(gameLevels *gameLevels) (*TextureHandler, error) {
+	registry, err := loadTextureRegistry(textureManifest)
+	if err != nil {
+		return nil, err
+	}
+	archetypes, err := loadSpriteArchetypes(spriteArchetypeFile, registry)
+	if err != nil {
+		return nil, err
+	}
+
 	t := &TextureHandler{
-		gameLevels:              gameLevels,
-		wallTextures:            make([]*ebiten.Image, numWallTextures),
-		floorAndCeilingTextures: make([]*image.RGBA, numFloorAndCeilingTextures),
-		spriteTextures:          make([]*ebiten.Image, numSpriteTextures),
+		gameLevels:    gameLevels,
+		registry:      registry,
+		archetypes:    archetypes,
+		levelTextures: make([]*levelTextures, len(gameLevels.levelMaps)),
+		doorFrames:    make(map[*Door]*doorFrame),
+	}
+
+	var errs []error
+	for i, m := range gameLevels.levelMaps {
+		lt, err := registry.resolveLevel(m)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("level%d.map: %w", i, err))
+		}
+		t.levelTextures[i] = lt
+		for _, p := range m.spritePlacements {
+			a, ok := archetypes[p.archetype]
+			if !ok {
+				errs = append(errs, fmt.Errorf("level%d.map: unknown sprite archetype %q", i, p.archetype))
+			} else if len(p.waypoints) > 0 && a.ai == nil {
+				errs = append(errs, fmt.Errorf("level%d.map: sprite archetype %q has waypoints but no AI", i, p.archetype))
+			}
+		}
 	}
-	t.loadTextureFiles()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
 	t.loadSprites()
-	return t
+	return t, nil
+}
+
+// current returns the resolved textures of the current level
+func (t *TextureHandler) current() *levelTextures {
+	return t.levelTextures[t.gameLevels.currentLevel]
 }
 
 func (t *TextureHandler) TextureAt(x int, y int, levelNum int, side int) *ebiten.Image {
-	texNum := -1
+	texNum := 0
 
 	mapLevel := t.gameLevels.levelMaps[t.gameLevels.currentLevel]
 
 	if x >= 0 && x < mapLevel.xLength && y >= 0 && y < mapLevel.yLength {
-		texNum = mapLevel.wallMaps[levelNum][x][y] - 1 // 1 subtracted from it so that texture 0 can be used
+		texNum = mapLevel.wallMaps[levelNum][x][y]
 	}
-	if texNum < 0 {
+	if texNum <= 0 {
 		return nil
 	}
-	return t.wallTextures[texNum]
+	if levelNum == 0 {
+		if door := mapLevel.doorAt(x, y); door != nil {
+			return t.doorTexture(door)
+		}
+	}
+	return t.wallTexture(texNum)
 }
 
-func (t *TextureHandler) FloorTextureAt(x, y, z int) *image.RGBA {
-	return t.floorAndCeilingTextures[0]
-	texNum := -1
-
-	mapLevel := t.gameLevels.levelMaps[t.gameLevels.currentLevel]
-
-	if x >= 0 && x < mapLevel.xLength && y >= 0 && y < mapLevel.yLength {
-		texNum = mapLevel.floorMap[x][y] - 1 // 1 subtracted from it so that texture 0 can be used
-	}
-	if texNum < 0 {
+// wallTexture returns the wall texture for a texture number used in the current level's grids
+func (t *TextureHandler) wallTexture(texNum int) *ebiten.Image {
+	walls := t.current().walls
+	if texNum <= 0 || texNum >= len(walls) {
 		return nil
 	}
-	return t.floorAndCeilingTextures[texNum]
+	return walls[texNum]
+}
+
+// FloorTextureAt returns the floor texture of the cell at Z-level z, or nil to show the static floor texture
+func (t *TextureHandler) FloorTextureAt(x, y, z int) *image.RGBA {
+	mapLevel := t.gameLevels.levelMaps[t.gameLevels.currentLevel]
+	return t.layerTextureAt(mapLevel.floorMaps, t.current().flats, x, y, z)
 }
 
+// CeilingTextureAt returns the ceiling texture of the cell at Z-level z, or nil where the cell is open to the sky
 func (t *TextureHandler) CeilingTextureAt(x, y, z int) *image.RGBA {
-	texNum := -1
+	mapLevel := t.gameLevels.levelMaps[t.gameLevels.currentLevel]
+	return t.layerTextureAt(mapLevel.ceilingMaps, t.current().flats, x, y, z)
+}
+
+// layerTextureAt looks up the texture number of the cell in a per level grid
+func (t *TextureHandler) layerTextureAt(layers [][][]int, textures []*image.RGBA, x, y, z int) *image.RGBA {
+	texNum := 0
 
 	mapLevel := t.gameLevels.levelMaps[t.gameLevels.currentLevel]
 
-	if x >= 0 && x < mapLevel.xLength && y >= 0 && y < mapLevel.yLength {
-		texNum = mapLevel.ceilingMap[x][y] - 1 // 1 subtracted from it so that texture 0 can be used
+	if z >= 0 && z < len(layers) && x >= 0 && x < mapLevel.xLength && y >= 0 && y < mapLevel.yLength {
+		texNum = layers[z][x][y]
 	}
-	if texNum < 0 {
+	if texNum <= 0 || texNum >= len(textures) {
 		return nil
 	}
-	return t.floorAndCeilingTextures[texNum]
+	return textures[texNum]
 }
 
+// MidTextureAt returns the mid texture of the cell at Z-level z, or nil if it has none
 func (t *TextureHandler) MidTextureAt(x, y, z int) *image.RGBA {
-	if x == 5 && y == 5 {
-		return t.floorAndCeilingTextures[0]
-	}
-	return nil
+	mapLevel := t.gameLevels.levelMaps[t.gameLevels.currentLevel]
+	return t.layerTextureAt(mapLevel.midMaps, t.current().mids, x, y, z)
 }
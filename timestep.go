@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+const (
+	defaultTPS = 60
+	// below this the distance moved each tick gets too large for collisions to be reliable
+	minTPS = 10
+)
+
+// The game world is updated in fixed steps, ebiten calls Update tps times per second however
+// fast frames are drawn and catches up with extra calls when it falls behind. All speeds and
+// durations are in seconds and scaled by the length of a tick, so the game plays the same at
+// any tick rate.
+
+// setTPS changes how many times per second the game world is updated
+func (g *Game) setTPS(tps int) {
+	g.dt = 1 / float64(tps)
+	ebiten.SetTPS(tps)
+}
+
+// playerView is where the camera is placed for the player at the end of a tick
+type playerView struct {
+	pos   geom.Vector2
+	z     float64
+	angle float64
+	pitch float64
+}
+
+func (g *Game) currentPlayerView() playerView {
+	return playerView{
+		pos:   *g.player.Position,
+		z:     g.player.PositionZ + g.player.CameraZ,
+		angle: g.player.Angle,
+		pitch: g.player.Pitch,
+	}
+}
+
+// interpolatedView returns the view part of the way from the previous tick to the latest one,
+// by how much of a tick has passed since the latest one. Frames drawn between ticks then move
+// smoothly when drawing faster than the tick rate, at the cost of running one tick behind.
+func (g *Game) interpolatedView() playerView {
+	t := geom.Clamp(time.Since(g.lastTick).Seconds()/g.dt, 0, 1)
+	prev, next := g.prevView, g.view
+	return playerView{
+		pos: geom.Vector2{
+			X: prev.pos.X + (next.pos.X-prev.pos.X)*t,
+			Y: prev.pos.Y + (next.pos.Y-prev.pos.Y)*t,
+		},
+		z: prev.z + (next.z-prev.z)*t,
+		// turn the short way around
+		angle: prev.angle + math.Remainder(next.angle-prev.angle, geom.Pi2)*t,
+		pitch: prev.pitch + (next.pitch-prev.pitch)*t,
+	}
+}
+
+// setCameraView places the camera at the given view
+func (g *Game) setCameraView(v playerView) {
+	g.camera.SetPosition(&geom.Vector2{X: v.pos.X, Y: v.pos.Y})
+	g.camera.SetPositionZ(v.z)
+	g.camera.SetHeadingAngle(v.angle)
+	g.camera.SetPitchAngle(v.pitch)
+}
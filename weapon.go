@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+type weaponState int
+
+const (
+	weaponReady weaponState = iota
+	weaponFiring
+	weaponReloading
+)
+
+// weaponFrame is one step of the firing animation, applied as a transform of the weapon image
+type weaponFrame struct {
+	offsetX, offsetY float64
+	rotation         float64
+	flash            bool
+}
+
+type Weapon struct {
+	Name       string
+	Damage     float64
+	Range      float64
+	ClipSize   int
+	Ammo       int
+	Reserve    int
+	FrameTime  float64 // seconds each frame of the firing animation is shown
+	ReloadTime float64 // seconds taken to reload
+
+	texture    *ebiten.Image
+	scale      float64
+	flashPoint geom.Vector2 // muzzle position in unscaled image pixels
+	frames     []weaponFrame
+
+	state     weaponState
+	stateTime float64 // seconds spent in the current state
+}
+
+func NewRevolver() *Weapon {
+	return &Weapon{
+		Name:       "revolver",
+		Damage:     25,
+		Range:      20,
+		ClipSize:   6,
+		Ammo:       6,
+		Reserve:    24,
+		FrameTime:  1.0 / 15,
+		ReloadTime: 1.5,
+		texture:    getSpriteFromFile("revolver.png"),
+		scale:      2,
+		flashPoint: geom.Vector2{X: 18, Y: 22},
+		frames: []weaponFrame{
+			{offsetX: 6, offsetY: -14, rotation: -0.12, flash: true},
+			{offsetX: 4, offsetY: -10, rotation: -0.08, flash: true},
+			{offsetX: 2, offsetY: -5, rotation: -0.04},
+			{offsetX: 1, offsetY: -2, rotation: -0.01},
+		},
+	}
+}
+
+// CanFire returns true if the weapon is ready and loaded
+func (w *Weapon) CanFire() bool {
+	return w.state == weaponReady && w.Ammo > 0
+}
+
+// Fire uses one round and starts the firing animation, returning false if it could not fire
+func (w *Weapon) Fire() bool {
+	if !w.CanFire() {
+		return false
+	}
+	w.Ammo--
+	w.state = weaponFiring
+	w.stateTime = 0
+	return true
+}
+
+// Reload starts reloading if the clip is not full and there is reserve ammo
+func (w *Weapon) Reload() bool {
+	if w.state != weaponReady || w.Ammo >= w.ClipSize || w.Reserve <= 0 {
+		return false
+	}
+	w.state = weaponReloading
+	w.stateTime = 0
+	return true
+}
+
+// Update advances firing and reloading by dt seconds
+func (w *Weapon) Update(dt float64) {
+	switch w.state {
+	case weaponFiring:
+		w.stateTime += dt
+		if w.stateTime >= float64(len(w.frames))*w.FrameTime {
+			w.state = weaponReady
+		}
+	case weaponReloading:
+		w.stateTime += dt
+		if w.stateTime >= w.ReloadTime {
+			rounds := int(math.Min(float64(w.ClipSize-w.Ammo), float64(w.Reserve)))
+			w.Ammo += rounds
+			w.Reserve -= rounds
+			w.state = weaponReady
+		}
+	}
+}
+
+// frame returns the current animation transform of the weapon
+func (w *Weapon) frame() weaponFrame {
+	switch w.state {
+	case weaponFiring:
+		return w.frames[min(int(w.stateTime/w.FrameTime), len(w.frames)-1)]
+	case weaponReloading:
+		// lower the weapon out of view and bring it back up
+		progress := w.stateTime / w.ReloadTime
+		drop := math.Sin(progress*math.Pi) * float64(w.texture.Bounds().Dy()) * w.scale
+		return weaponFrame{offsetY: drop}
+	}
+	return weaponFrame{}
+}
+
+// hitScan traces a ray from the player's view through the level, returning the first
+// sprite hit before any wall (or nil), and the point and distance where the ray stopped
+func (g *Game) hitScan(maxDist float64) (*Sprite, *geom.Vector2, float64) {
+	currentMap := g.gameLevels.levelMaps[g.gameLevels.currentLevel]
+	pos := g.player.Position
+	ray := geom.LineFromAngle(pos.X, pos.Y, g.player.Angle, maxDist)
+
+	minX, maxX := math.Min(ray.X1, ray.X2), math.Max(ray.X1, ray.X2)
+	minY, maxY := math.Min(ray.Y1, ray.Y2), math.Max(ray.Y1, ray.Y2)
+
+	rayZ := g.player.PositionZ + g.player.CameraZ
+	slope := math.Tan(g.player.Pitch)
+
+	// nearest wall face or opaque mid texture at the height the ray crosses it, rather than the
+	// collision lines which stand out from the walls
+	wall := currentMap.castRay(pos.X, pos.Y, rayZ, g.player.Angle, slope, maxDist)
+	hitDist := wall.distance
+	hitPoint := &geom.Vector2{X: wall.point.X, Y: wall.point.Y}
+
+	// nearest sprite in front of the wall, also checking the ray height at the sprite
+	var hitSprite *Sprite
+	for _, sprite := range currentMap.spritesNear(minX, minY, maxX, maxY) {
+		if sprite.CollisionRadius <= 0 {
+			continue
+		}
+		circle := geom.Circle{X: sprite.Position.X, Y: sprite.Position.Y, Radius: sprite.CollisionRadius}
+		for _, p := range geom.LineCircleIntersection(ray, circle, true) {
+			d := geom.Distance(pos.X, pos.Y, p.X, p.Y)
+			if d >= hitDist {
+				continue
+			}
+			z := rayZ + slope*d
+			minZ, maxZ := zEntityMinMax(sprite.PositionZ, sprite.Entity)
+			if z < minZ || z > maxZ {
+				continue
+			}
+			hitDist = d
+			hitPoint = &geom.Vector2{X: p.X, Y: p.Y}
+			hitSprite = sprite
+		}
+	}
+
+	return hitSprite, hitPoint, hitDist
+}
+
+// fireWeapon fires the player's weapon and applies damage to whatever it hits
+func (g *Game) fireWeapon() {
+	w := g.weapon
+	if !w.Fire() {
+		if w.state == weaponReady && w.Ammo == 0 {
+			if !w.Reload() {
+				g.showMessage("Out of ammo")
+			}
+		}
+		return
+	}
+
+	hitSprite, _, _ := g.hitScan(w.Range)
+	if hitSprite != nil {
+		g.damage(hitSprite.Entity, g.player.Entity, w.Damage)
+	}
+}
+
+var muzzleFlashColor = color.RGBA{255, 220, 120, 220}
+
+// drawWeapon draws the weapon in the bottom right of the screen with its ammo count
+func (g *Game) drawWeapon(screen *ebiten.Image) {
+	w := g.weapon
+	f := w.frame()
+	imgW, imgH := float64(w.texture.Bounds().Dx()), float64(w.texture.Bounds().Dy())
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(w.scale, w.scale)
+	// recoil rotates around the grip at the bottom right
+	op.GeoM.Translate(-imgW*w.scale, -imgH*w.scale)
+	op.GeoM.Rotate(f.rotation)
+	op.GeoM.Translate(float64(g.screenWidth)+f.offsetX, float64(g.screenHeight)+f.offsetY)
+
+	if f.flash {
+		fx, fy := op.GeoM.Apply(w.flashPoint.X, w.flashPoint.Y)
+		vector.DrawFilledCircle(screen, float32(fx), float32(fy), float32(8*w.scale), muzzleFlashColor, true)
+	}
+	screen.DrawImage(w.texture, op)
+
+	ammo := fmt.Sprintf("%s %d/%d", w.Name, w.Ammo, w.Reserve)
+	if w.state == weaponReloading {
+		ammo = "reloading..."
+	}
+	ebitenutil.DebugPrintAt(screen, ammo, g.screenWidth-len(ammo)*6-10, g.screenHeight-int(imgH*w.scale)-20)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/harbdog/raycaster-go"
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// shootingGame returns a game in a walled 5x8 room with the player at (1.5,1.5) looking along y
+// towards the far wall face at y=7
+func shootingGame(t *testing.T) *Game {
+	t.Helper()
+	m, err := parseMap(strings.NewReader(`walltexture 1 stone
+walls
+11111111
+10000001
+10000001
+10000001
+11111111
+end
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.clearSprites()
+
+	g := &Game{gameLevels: &gameLevels{levelMaps: []*Map{m}}}
+	g.player = newSpawnedPlayer(m)
+	g.player.Angle = math.Pi / 2
+	return g
+}
+
+func TestHitScanStopsAtWallFace(t *testing.T) {
+	g := shootingGame(t)
+	sprite, point, dist := g.hitScan(20)
+	if sprite != nil {
+		t.Errorf("hit sprite %v in an empty room", sprite.Position)
+	}
+	if math.Abs(dist-5.5) > 1e-9 || math.Abs(point.Y-7) > 1e-9 {
+		t.Errorf("stopped at %v after %v, want the wall face at y=7 after 5.5", point, dist)
+	}
+}
+
+func TestHitScanSpriteAgainstWall(t *testing.T) {
+	g := shootingGame(t)
+	m := g.gameLevels.levelMaps[0]
+	// the front of the sprite is closer to the wall than the wall's collision lines
+	target := &Sprite{Entity: &Entity{
+		Position:        &geom.Vector2{X: 1.5, Y: 6.95},
+		Anchor:          raycaster.AnchorBottom,
+		CollisionRadius: 0.1,
+		CollisionHeight: 1,
+	}}
+	m.addSprite(target)
+
+	sprite, _, dist := g.hitScan(20)
+	if sprite != target {
+		t.Fatalf("missed the sprite standing against the wall, stopped after %v", dist)
+	}
+	if math.Abs(dist-5.35) > 1e-9 {
+		t.Errorf("hit after %v, want 5.35", dist)
+	}
+}